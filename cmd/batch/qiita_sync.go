@@ -42,37 +42,57 @@ func main() {
 	}
 	repo := dynamo.NewLeafDynamoRepository(dynamoClient, tableName)
 
-	// 既存LeafのURL一覧を取得して差分同期
-	leaves, err := repo.List(ctx, domain.ListOptions{Limit: 1000})
+	// 同期先の利用者（未指定なら単一ユーザー運用の利用者me）
+	syncUser := os.Getenv("QIITA_SYNC_USER_ID")
+	if syncUser == "" {
+		syncUser = "me"
+	}
+	userID, err := domain.NewUserID(syncUser)
 	if err != nil {
-		fmt.Println("DynamoDB取得エラー:", err)
+		fmt.Println("QIITA_SYNC_USER_IDが不正です:", err)
 		os.Exit(1)
 	}
-	existingURLs := make(map[string]struct{})
-	for _, leaf := range leaves {
-		existingURLs[leaf.URL().String()] = struct{}{}
+	ctx = domain.WithUserID(ctx, userID)
+
+	// タグはAPIからの登録と同じく正規化し、利用者の別名を反映する
+	policy, err := domain.ParseTagPolicy(os.Getenv("TAG_FOLD_CASE"), os.Getenv("TAG_MAX_LENGTH"), os.Getenv("TAG_ALLOWED_CHARS"))
+	if err != nil {
+		fmt.Println("タグの設定エラー:", err)
+		os.Exit(1)
+	}
+	aliases, err := dynamo.NewTagAliasDynamoRepository(dynamoClient, tableName).ListAliases(ctx)
+	if err != nil {
+		fmt.Println("タグの別名の取得エラー:", err)
+		os.Exit(1)
 	}
+	normalizer := domain.NewTagNormalizer(policy, aliases)
 
-	countNew := 0
+	// 登録済みのURLは一括作成で読み飛ばされるので差分同期になる
+	syncedAt := time.Now()
+	leaves := make([]*domain.Leaf, 0, len(items))
 	for _, item := range items {
-		if _, exists := existingURLs[item.URL]; exists {
-			continue // 既存記事はスキップ
-		}
 		tags := make([]string, len(item.Tags))
 		for i, t := range item.Tags {
 			tags[i] = t.Name
 		}
-		leaf, err := domain.NewLeaf(item.Title, item.URL, "qiita", tags, false)
+		normalized, err := normalizer.NormalizeAll(tags)
 		if err != nil {
-			fmt.Println("Leaf生成エラー:", err)
+			fmt.Println("タグの正規化エラー:", item.URL, err)
 			continue
 		}
-		_, err = repo.Put(ctx, leaf)
+		leaf, err := domain.NewLeaf(item.Title, "", "", item.URL, domain.PlatformQiita, normalized, false)
 		if err != nil {
-			fmt.Println("DynamoDB保存エラー:", err)
+			fmt.Println("Leaf生成エラー:", err)
+			continue
 		}
-		countNew++
-		time.Sleep(200 * time.Millisecond) // API制限対策
+		leaf.MarkSynced(syncedAt)
+		leaves = append(leaves, leaf)
+	}
+	stored, err := repo.PutMany(ctx, leaves)
+	if err != nil {
+		fmt.Println("DynamoDB保存エラー:", err)
+		os.Exit(1)
 	}
+	countNew := len(stored)
 	fmt.Printf("Qiitaストック記事の同期が完了しました（新規追加: %d件）\n", countNew)
 }
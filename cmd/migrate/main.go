@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/dynamo"
+)
+
+func main() {
+	ctx := context.Background()
+	status := flag.Bool("status", false, "現在のスキーマバージョンを表示して終了")
+	flag.Parse()
+
+	_ = godotenv.Load() // 本番は.env不要なのでエラー無視
+
+	dynamoClient, tableName, err := dynamo.NewDynamoClientAndTable(ctx)
+	if err != nil {
+		panic(err)
+	}
+	migrator := dynamo.NewMigrator(dynamoClient, tableName)
+
+	if *status {
+		version, err := migrator.CurrentVersion(ctx)
+		if err != nil {
+			fmt.Println("スキーマバージョン取得エラー:", err)
+			os.Exit(1)
+		}
+		latest := 0
+		if n := len(migrator.Migrations); n > 0 {
+			latest = migrator.Migrations[n-1].Version
+		}
+		fmt.Printf("スキーマバージョン: %d（最新: %d）\n", version, latest)
+		return
+	}
+
+	applied, err := migrator.Migrate(ctx)
+	for _, m := range applied {
+		fmt.Printf("適用: %d %s\n", m.Version, m.Description)
+	}
+	if err != nil {
+		fmt.Println("マイグレーションエラー:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("マイグレーションが完了しました（適用: %d件）\n", len(applied))
+}
@@ -5,9 +5,9 @@ import (
 )
 
 func main() {
-	leafHandler, port := server.InitializeDependencies()
-	r := server.NewRouter(leafHandler)
-	if err := r.Run(":" + port); err != nil {
+	deps := server.InitializeDependencies()
+	r := server.NewRouter(deps)
+	if err := r.Run(":" + deps.Port); err != nil {
 		panic("failed to start server: " + err.Error())
 	}
 }
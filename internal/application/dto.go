@@ -7,21 +7,49 @@ import (
 )
 
 type LeafInputDTO struct {
-	ID       string
-	Note     string
-	URL      string
-	Platform string
-	Tags     []string
+	Title       string
+	Description string
+	Note        string // 利用者自身のメモ（Markdown）
+	URL         string
+	Platform    string
+	Tags        []string
+}
+
+// Leafの更新（URLは変更できない）
+type LeafUpdateDTO struct {
+	ID          string
+	Title       string
+	Description *string // nilなら変更しない（空文字なら消す）
+	Note        *string // nilなら変更しない（空文字なら消す）
+	Platform    string
+	Tags        *[]string // nilなら変更しない（空なら消す）
+	Version     int       // クライアントが前提とするバージョン（AnyVersionなら指定なし）
 }
 
 type LeafOutputDTO struct {
-	ID       string
-	Note     string
-	URL      string
-	Platform string
-	Read     bool
-	Tags     []string
-	SyncedAt string
+	ID          string
+	Title       string
+	Description string
+	Note        string
+	URL         string
+	Platform    string
+	Read        bool   // 読み終えたか（読了・アーカイブ済み）
+	Status      string // unread, reading, read, archived
+	StartedAt   string // 各状態に遷移した日時（その状態を経ていなければ空）
+	ReadAt      string
+	ArchivedAt  string
+	Tags        []string
+	// URLのページから取得した情報（取得していなければ空）
+	SiteName     string
+	CanonicalURL string
+	ImageURL     string
+	PublishedAt  string
+	FetchedAt    string
+	CreatedAt    string
+	UpdatedAt    string
+	SyncedAt     string // 外部サービスから同期した日時（同期していなければ空）
+	DeletedAt    string // ゴミ箱に移した日時（ゴミ箱になければ空）
+	Version      int
 }
 
 func LeafDomainToOutputDTO(leaf *domain.Leaf) *LeafOutputDTO {
@@ -31,13 +59,105 @@ func LeafDomainToOutputDTO(leaf *domain.Leaf) *LeafOutputDTO {
 		tagStrings[i] = tag.String()
 	}
 
-	return &LeafOutputDTO{
-		ID:       leaf.ID().String(),
-		Note:     leaf.Note(),
-		URL:      leaf.URL().String(),
-		Platform: leaf.Platform(),
-		Read:     leaf.Read(),
-		Tags:     tagStrings,
-		SyncedAt: leaf.SyncedAt().Format(time.RFC3339),
+	dto := &LeafOutputDTO{
+		ID:          leaf.ID().String(),
+		Title:       leaf.Title(),
+		Description: leaf.Description(),
+		Note:        leaf.Note(),
+		URL:         leaf.URL().String(),
+		Platform:    leaf.Platform(),
+		Read:        leaf.Read(),
+		Status:      leaf.Status().String(),
+		Tags:        tagStrings,
+		CreatedAt:   formatTime(leaf.CreatedAt()),
+		UpdatedAt:   formatTime(leaf.UpdatedAt()),
+		SyncedAt:    formatTime(leaf.SyncedAt()),
+		Version:     leaf.Version(),
+	}
+	reading := leaf.ReadingState()
+	dto.StartedAt = formatTime(reading.StartedAt)
+	dto.ReadAt = formatTime(reading.ReadAt)
+	dto.ArchivedAt = formatTime(reading.ArchivedAt)
+	page := leaf.Page()
+	dto.SiteName = page.SiteName
+	dto.CanonicalURL = page.CanonicalURL
+	dto.ImageURL = page.ImageURL
+	dto.PublishedAt = formatTime(page.PublishedAt)
+	dto.FetchedAt = formatTime(page.FetchedAt)
+	if leaf.Trashed() {
+		dto.DeletedAt = leaf.DeletedAt().Format(time.RFC3339)
+	}
+	return dto
+}
+
+// 日時の出力（ゼロ値なら空）
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+type SearchResultOutputDTO struct {
+	*LeafOutputDTO
+	Score float64 // 検索語への一致の度合い（大きいほど上位）
+}
+
+func SearchResultToOutputDTO(r SearchResult) *SearchResultOutputDTO {
+	return &SearchResultOutputDTO{
+		LeafOutputDTO: LeafDomainToOutputDTO(r.Leaf),
+		Score:         r.Score,
+	}
+}
+
+type TagOutputDTO struct {
+	Name   string
+	Count  int // タグが付いたLeafの数（ゴミ箱のLeafは除く）
+	Unread int // うち未読のLeafの数
+}
+
+func TagCountToOutputDTO(c domain.TagCount) *TagOutputDTO {
+	return &TagOutputDTO{
+		Name:   c.Tag,
+		Count:  c.Leaves,
+		Unread: c.Unread,
+	}
+}
+
+type TagNodeOutputDTO struct {
+	Name        string // 最下位の階層の名前
+	Path        string // 最上位からのタグ
+	Count       int    // このタグが付いたLeafの数
+	Unread      int
+	TotalCount  int // 子孫のタグを含めた件数の合計
+	TotalUnread int
+	Children    []*TagNodeOutputDTO
+}
+
+func TagNodeToOutputDTO(n *domain.TagNode) *TagNodeOutputDTO {
+	children := make([]*TagNodeOutputDTO, len(n.Children))
+	for i, child := range n.Children {
+		children[i] = TagNodeToOutputDTO(child)
+	}
+	return &TagNodeOutputDTO{
+		Name:        n.Name,
+		Path:        n.Path,
+		Count:       n.Leaves,
+		Unread:      n.Unread,
+		TotalCount:  n.TotalLeaves,
+		TotalUnread: n.TotalUnread,
+		Children:    children,
+	}
+}
+
+type TagAliasOutputDTO struct {
+	Alias string
+	Tag   string
+}
+
+func TagAliasToOutputDTO(a domain.TagAlias) *TagAliasOutputDTO {
+	return &TagAliasOutputDTO{
+		Alias: a.Alias().String(),
+		Tag:   a.Tag().String(),
 	}
 }
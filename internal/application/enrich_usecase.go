@@ -0,0 +1,116 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// メタデータの取得を待っているLeafの最大数（超えた分は取得しない）
+const enrichQueueSize = 100
+
+// 1件のLeafのメタデータの取得と保存にかける時間の上限
+const enrichTimeout = 30 * time.Second
+
+// 他の更新と競合したLeafを読み直して保存をやり直す回数
+const maxEnrichRetries = 3
+
+// 登録したLeafのページのメタデータ（タイトル・説明・サイト名など）を取得して補う
+// AddLeafを待たせないよう、取得と保存はワーカーで非同期に行う
+type LeafEnricher struct {
+	repo    domain.LeafRepository
+	fetcher domain.PageMetadataFetcher
+	jobs    chan enrichJob
+	mu      sync.RWMutex // closedとjobsへの送信を守る
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+type enrichJob struct {
+	userID domain.UserID
+	leafID string
+}
+
+// workers個のワーカーを起動する
+func NewLeafEnricher(repo domain.LeafRepository, fetcher domain.PageMetadataFetcher, workers int) *LeafEnricher {
+	e := &LeafEnricher{repo: repo, fetcher: fetcher, jobs: make(chan enrichJob, enrichQueueSize)}
+	for range max(workers, 1) {
+		e.wg.Add(1)
+		go e.work()
+	}
+	return e
+}
+
+// コンテキストの利用者のLeafのメタデータの取得を予約する
+// 待ち行列が一杯、または停止済みなら予約せずにfalseを返す（呼び出し元は待たされない）
+func (e *LeafEnricher) Enqueue(ctx context.Context, leafID string) bool {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return false
+	}
+	select {
+	case e.jobs <- enrichJob{userID: userID, leafID: leafID}:
+		return true
+	default:
+		return false
+	}
+}
+
+// 予約の受け付けをやめ、予約済みの取得が終わるまで待つ
+func (e *LeafEnricher) Close() {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.jobs)
+	}
+	e.mu.Unlock()
+	e.wg.Wait()
+}
+
+func (e *LeafEnricher) work() {
+	defer e.wg.Done()
+	for job := range e.jobs {
+		// リクエストが終わっても取得を続けられるよう、利用者だけを引き継いだコンテキストで実行する
+		ctx, cancel := context.WithTimeout(domain.WithUserID(context.Background(), job.userID), enrichTimeout)
+		if _, err := e.Enrich(ctx, job.leafID); err != nil && !errors.Is(err, domain.ErrLeafNotFound) {
+			log.Printf("Leaf %s のメタデータの取得に失敗しました: %v", job.leafID, err)
+		}
+		cancel()
+	}
+}
+
+// Leafのページのメタデータを取得し、まだ値のない項目を補って保存する
+func (e *LeafEnricher) Enrich(ctx context.Context, leafID string) (*domain.Leaf, error) {
+	leaf, err := e.repo.Get(ctx, leafID)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := e.fetcher.Fetch(ctx, leaf.URL().String())
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		leaf.Enrich(*meta, time.Now())
+		err = e.repo.Update(ctx, leaf)
+		if !errors.Is(err, domain.ErrVersionConflict) || attempt >= maxEnrichRetries {
+			if err != nil {
+				return nil, err
+			}
+			return leaf, nil
+		}
+		// 取得している間に利用者が編集していたら、読み直して編集後の内容に補う
+		leaf, err = e.repo.Get(ctx, leafID)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
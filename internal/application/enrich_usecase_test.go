@@ -0,0 +1,206 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/memory"
+)
+
+// 決まったメタデータを返すFetcher（beforeがあれば返す前に呼ぶ）
+type stubFetcher struct {
+	meta   domain.PageMetadata
+	err    error
+	before func(ctx context.Context)
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, url string) (*domain.PageMetadata, error) {
+	if f.before != nil {
+		f.before(ctx)
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	meta := f.meta
+	return &meta, nil
+}
+
+// 指定した回数だけUpdateをErrVersionConflictにするリポジトリ
+type conflictingRepository struct {
+	domain.LeafRepository
+	conflicts int
+	updates   int
+}
+
+func (r *conflictingRepository) Update(ctx context.Context, update *domain.Leaf) error {
+	r.updates++
+	if r.conflicts > 0 {
+		r.conflicts--
+		return domain.ErrVersionConflict
+	}
+	return r.LeafRepository.Update(ctx, update)
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	id, err := domain.NewUserID("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return domain.WithUserID(context.Background(), id)
+}
+
+func putLeaf(t *testing.T, ctx context.Context, repo domain.LeafRepository, title, description string) *domain.Leaf {
+	t.Helper()
+	leaf, err := domain.NewLeaf(title, description, "", "https://example.com/articles/1", "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := repo.Put(ctx, leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+var pageMeta = domain.PageMetadata{
+	Title:        "ページのタイトル",
+	Description:  "ページの説明",
+	SiteName:     "Example",
+	CanonicalURL: "https://example.com/articles/1",
+	ImageURL:     "https://example.com/1.png",
+	PublishedAt:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func TestLeafEnricherEnrich(t *testing.T) {
+	t.Run("空の項目とURLのままのタイトルを補う", func(t *testing.T) {
+		ctx := testContext(t)
+		repo := memory.NewLeafMemoryRepository()
+		leaf := putLeaf(t, ctx, repo, "", "")
+		e := &LeafEnricher{repo: repo, fetcher: &stubFetcher{meta: pageMeta}}
+
+		if _, err := e.Enrich(ctx, leaf.ID().String()); err != nil {
+			t.Fatalf("Enrich: %v", err)
+		}
+		got, err := repo.Get(ctx, leaf.ID().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title() != pageMeta.Title || got.Description() != pageMeta.Description {
+			t.Errorf("title, description = %q, %q", got.Title(), got.Description())
+		}
+		if page := got.Page(); page.SiteName != "Example" || page.ImageURL != pageMeta.ImageURL || !page.PublishedAt.Equal(pageMeta.PublishedAt) || !got.Enriched() {
+			t.Errorf("Page = %+v", page)
+		}
+	})
+
+	t.Run("利用者が入力したタイトルと説明は上書きしない", func(t *testing.T) {
+		ctx := testContext(t)
+		repo := memory.NewLeafMemoryRepository()
+		leaf := putLeaf(t, ctx, repo, "自分のタイトル", "自分の説明")
+		e := &LeafEnricher{repo: repo, fetcher: &stubFetcher{meta: pageMeta}}
+
+		got, err := e.Enrich(ctx, leaf.ID().String())
+		if err != nil {
+			t.Fatalf("Enrich: %v", err)
+		}
+		if got.Title() != "自分のタイトル" || got.Description() != "自分の説明" {
+			t.Errorf("title, description = %q, %q", got.Title(), got.Description())
+		}
+		if got.Page().SiteName != "Example" {
+			t.Errorf("SiteName = %q, want Example", got.Page().SiteName)
+		}
+	})
+
+	t.Run("取得中の編集と競合したら読み直して補う", func(t *testing.T) {
+		ctx := testContext(t)
+		repo := memory.NewLeafMemoryRepository()
+		leaf := putLeaf(t, ctx, repo, "", "")
+		id := leaf.ID().String()
+		// 取得している間に利用者がタイトルを編集する
+		fetcher := &stubFetcher{meta: pageMeta, before: func(ctx context.Context) {
+			edited, err := repo.Get(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := edited.UpdateTitle("編集したタイトル"); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.Update(ctx, edited); err != nil {
+				t.Fatal(err)
+			}
+		}}
+		e := &LeafEnricher{repo: repo, fetcher: fetcher}
+
+		got, err := e.Enrich(ctx, id)
+		if err != nil {
+			t.Fatalf("Enrich: %v", err)
+		}
+		stored, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range []*domain.Leaf{got, stored} {
+			if l.Title() != "編集したタイトル" || l.Description() != pageMeta.Description || l.Version() != 3 {
+				t.Errorf("title, description, version = %q, %q, %d", l.Title(), l.Description(), l.Version())
+			}
+		}
+	})
+
+	t.Run("競合が続けば諦めてErrVersionConflict", func(t *testing.T) {
+		ctx := testContext(t)
+		repo := &conflictingRepository{LeafRepository: memory.NewLeafMemoryRepository(), conflicts: maxEnrichRetries + 1}
+		leaf := putLeaf(t, ctx, repo, "", "")
+		e := &LeafEnricher{repo: repo, fetcher: &stubFetcher{meta: pageMeta}}
+
+		if _, err := e.Enrich(ctx, leaf.ID().String()); !errors.Is(err, domain.ErrVersionConflict) {
+			t.Errorf("Enrich error = %v, want ErrVersionConflict", err)
+		}
+		if repo.updates != maxEnrichRetries+1 {
+			t.Errorf("updates = %d, want %d", repo.updates, maxEnrichRetries+1)
+		}
+	})
+
+	t.Run("取得に失敗したら保存しない", func(t *testing.T) {
+		ctx := testContext(t)
+		repo := &conflictingRepository{LeafRepository: memory.NewLeafMemoryRepository()}
+		leaf := putLeaf(t, ctx, repo, "", "")
+		fetchErr := errors.New("fetch failed")
+		e := &LeafEnricher{repo: repo, fetcher: &stubFetcher{err: fetchErr}}
+
+		if _, err := e.Enrich(ctx, leaf.ID().String()); !errors.Is(err, fetchErr) {
+			t.Errorf("Enrich error = %v, want %v", err, fetchErr)
+		}
+		if repo.updates != 0 {
+			t.Errorf("updates = %d, want 0", repo.updates)
+		}
+	})
+}
+
+func TestLeafEnricherEnqueue(t *testing.T) {
+	ctx := testContext(t)
+	repo := memory.NewLeafMemoryRepository()
+	leaf := putLeaf(t, ctx, repo, "", "")
+	e := NewLeafEnricher(repo, &stubFetcher{meta: pageMeta}, 2)
+
+	if !e.Enqueue(ctx, leaf.ID().String()) {
+		t.Fatal("Enqueue = false, want true")
+	}
+	if e.Enqueue(context.Background(), leaf.ID().String()) {
+		t.Error("Enqueue without user = true, want false")
+	}
+	e.Close()
+	if e.Enqueue(ctx, leaf.ID().String()) {
+		t.Error("Enqueue after Close = true, want false")
+	}
+	got, err := repo.Get(ctx, leaf.ID().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title() != pageMeta.Title {
+		t.Errorf("Title = %q, want %q", got.Title(), pageMeta.Title)
+	}
+}
@@ -0,0 +1,144 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// 検索結果の既定の件数
+const DefaultSearchLimit = 20
+
+// 索引を作り直すときに1回に読み込むLeafの数
+const searchRebuildPageSize = 500
+
+// 全文検索で一致したLeaf
+type SearchResult struct {
+	Leaf  *domain.Leaf
+	Score float64
+}
+
+// 書き込みに合わせて全文検索の索引を更新するリポジトリ
+type indexedLeafRepository struct {
+	domain.LeafRepository
+	index domain.SearchIndex
+}
+
+// repoへの作成・更新・削除を索引にも反映するリポジトリを返す
+// LeafUsecaseとLeafEnricherには、このリポジトリを渡す
+// 別のプロセス（cmd/batchのQiita同期など）はこのリポジトリを通さずに書き込むため、その変更は
+// 索引を作り直すまで（RebuildSearchIndexか、索引の有効期限が過ぎるまで）検索に反映されない
+func NewIndexedLeafRepository(repo domain.LeafRepository, index domain.SearchIndex) domain.LeafRepository {
+	return &indexedLeafRepository{LeafRepository: repo, index: index}
+}
+
+func (r *indexedLeafRepository) Put(ctx context.Context, leaf *domain.Leaf) (*domain.Leaf, error) {
+	saved, err := r.LeafRepository.Put(ctx, leaf)
+	if err != nil {
+		return nil, err
+	}
+	r.index.Index(ctx, saved)
+	return saved, nil
+}
+
+func (r *indexedLeafRepository) PutMany(ctx context.Context, leaves []*domain.Leaf) ([]*domain.Leaf, error) {
+	stored, err := r.LeafRepository.PutMany(ctx, leaves)
+	for _, leaf := range stored {
+		r.index.Index(ctx, leaf)
+	}
+	return stored, err
+}
+
+func (r *indexedLeafRepository) Update(ctx context.Context, update *domain.Leaf) error {
+	if err := r.LeafRepository.Update(ctx, update); err != nil {
+		return err
+	}
+	r.index.Index(ctx, update)
+	return nil
+}
+
+func (r *indexedLeafRepository) Delete(ctx context.Context, id string) error {
+	if err := r.LeafRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.index.Remove(ctx, id)
+	return nil
+}
+
+// タイトル・説明・メモ・URL・タグを全文検索する（ゴミ箱のLeafは含まない）
+// 索引を作成していなければ、保存済みのLeafから作成してから検索する
+func (u *LeafUsecase) SearchLeaves(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, domain.ErrSearchQueryRequired
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if !u.search.Built(ctx) {
+		if _, err := u.RebuildSearchIndex(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// 索引に反映される前に他のプロセス（同期バッチなど）が削除・ゴミ箱に移したLeafは除くため、
+	// 除いた分だけ多く取り直してlimit件をそろえる
+	results := make([]SearchResult, 0, limit)
+	checked := make(map[string]bool)
+	for fetch := limit; ; fetch *= 2 {
+		hits, err := u.search.Search(ctx, query, fetch)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range hits {
+			if checked[hit.LeafID] {
+				continue
+			}
+			checked[hit.LeafID] = true
+			leaf, err := u.repo.Get(ctx, hit.LeafID)
+			if err != nil && !errors.Is(err, domain.ErrLeafNotFound) {
+				return nil, err
+			}
+			if err != nil || leaf.Trashed() {
+				// 次の検索では取り直さないよう索引から外す
+				u.search.Remove(ctx, hit.LeafID)
+				continue
+			}
+			results = append(results, SearchResult{Leaf: leaf, Score: hit.Score})
+			if len(results) == limit {
+				return results, nil
+			}
+		}
+		// 一致したLeafをすべて確かめた
+		if len(hits) < fetch {
+			return results, nil
+		}
+	}
+}
+
+// 利用者の全文検索の索引を保存済みのLeafから作り直し、索引に登録したLeafの数を返す
+// 他のプロセス（同期バッチなど）が書き込んだLeafを検索できるようにするときに使う
+func (u *LeafUsecase) RebuildSearchIndex(ctx context.Context) (int, error) {
+	var count int
+	err := u.search.Rebuild(ctx, func() ([]*domain.Leaf, error) {
+		var leaves []*domain.Leaf
+		opts := domain.ListOptions{Limit: searchRebuildPageSize}
+		for {
+			page, next, err := u.repo.List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			for i := range page {
+				leaves = append(leaves, &page[i])
+			}
+			if next == "" {
+				break
+			}
+			opts.Cursor = next
+		}
+		count = len(leaves)
+		return leaves, nil
+	})
+	return count, err
+}
@@ -0,0 +1,82 @@
+package application
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/memory"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/search"
+)
+
+func TestSearchLeavesSkipsStaleHits(t *testing.T) {
+	ctx := testContext(t)
+	store := memory.NewLeafMemoryRepository()
+	index := search.NewIndex()
+	repo := NewIndexedLeafRepository(store, index)
+	u := NewLeafUsecase(repo, memory.NewTagAliasMemoryRepository(), domain.TagPolicy{}, nil, index)
+
+	// 後で索引を通さずに消す7件を上位にする
+	var leaves []*domain.Leaf
+	for i := range 10 {
+		title := "DynamoDB DynamoDB"
+		if i >= 7 {
+			title = "DynamoDB の設計"
+		}
+		leaf, err := domain.NewLeaf(title, "", "", fmt.Sprintf("https://example.com/%d", i), "", nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved, err := repo.Put(ctx, leaf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, saved)
+	}
+	if _, err := u.RebuildSearchIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 他のプロセスが索引を通さずにゴミ箱へ移した・削除したLeaf
+	for _, leaf := range leaves[:6] {
+		if err := leaf.MoveToTrash(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Update(ctx, leaf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Delete(ctx, leaves[6].ID().String()); err != nil {
+		t.Fatal(err)
+	}
+	var stale []string
+	for _, leaf := range leaves[:7] {
+		stale = append(stale, leaf.ID().String())
+	}
+
+	if hits, _ := index.Search(ctx, "dynamodb", 3); slices.ContainsFunc(hits, func(h domain.SearchHit) bool { return !slices.Contains(stale, h.LeafID) }) {
+		t.Fatalf("上位3件が消したLeafではありません: %v", hits)
+	}
+
+	results, err := u.SearchLeaves(ctx, "dynamodb", 3)
+	if err != nil {
+		t.Fatalf("SearchLeaves: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3", len(results))
+	}
+	for _, r := range results {
+		if r.Leaf.Trashed() {
+			t.Errorf("trashed leaf %s in results", r.Leaf.ID())
+		}
+	}
+	// 取り直しで見つけた古いヒットは索引から外れる
+	hits, err := index.Search(ctx, "dynamodb", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 3 {
+		t.Errorf("len(index hits) = %d, want 3", len(hits))
+	}
+}
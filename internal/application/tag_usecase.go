@@ -0,0 +1,250 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// 利用者が使っているタグと件数
+func (u *LeafUsecase) ListTags(ctx context.Context) ([]domain.TagCount, error) {
+	return u.repo.ListTags(ctx)
+}
+
+// タグの階層の木（祖先のタグには子孫のタグの件数を合計する）
+func (u *LeafUsecase) TagTree(ctx context.Context) ([]*domain.TagNode, error) {
+	counts, err := u.repo.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return domain.BuildTagTree(counts), nil
+}
+
+// タグが付いたLeafを返す（他の絞り込み条件と組み合わせられる）
+// タグは保存済みのタグと完全に一致するもの、またはaws/*のような子孫のパターン
+func (u *LeafUsecase) ListTagLeaves(ctx context.Context, tag string, opts domain.ListOptions) ([]domain.Leaf, string, error) {
+	opts.Tags = []string{tag}
+	opts.TagMatch = domain.TagMatchAny
+	return u.repo.List(ctx, opts)
+}
+
+// タグの一括付け替え1回分で書き換えるLeafの既定の数
+const DefaultTagBatchSize = 100
+
+// 他の更新と競合したLeafを読み直して付け替えをやり直す回数
+const maxRetagRetries = 3
+
+// タグの一括付け替え1回分の結果
+// Doneがfalseなら、同じ操作をもう一度実行すると続きを処理する
+type TagOperationResult struct {
+	Processed int  // 今回書き換えたLeafの数
+	Done      bool // 対象のタグが付いたLeafが残っていなければtrue
+}
+
+// タグの改名
+func (u *LeafUsecase) RenameTag(ctx context.Context, from, to string, limit int) (*TagOperationResult, error) {
+	normalizer, err := u.TagNormalizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	to, err = normalizer.Normalize("to", to)
+	if err != nil {
+		return nil, err
+	}
+	r, err := domain.NewTagRename(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return u.replaceTags(ctx, r, limit)
+}
+
+// 複数のタグを1つに統合
+func (u *LeafUsecase) MergeTags(ctx context.Context, sources []string, target string, limit int) (*TagOperationResult, error) {
+	normalizer, err := u.TagNormalizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target, err = normalizer.Normalize("target", target)
+	if err != nil {
+		return nil, err
+	}
+	r, err := domain.NewTagMerge(sources, target)
+	if err != nil {
+		return nil, err
+	}
+	return u.replaceTags(ctx, r, limit)
+}
+
+// タグとその子孫をparentの下へ移動する（parentが空なら最上位へ）
+func (u *LeafUsecase) MoveTag(ctx context.Context, tag, parent string, limit int) (*TagOperationResult, error) {
+	normalizer, err := u.TagNormalizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if parent != "" {
+		if parent, err = normalizer.Normalize("parent", parent); err != nil {
+			return nil, err
+		}
+	}
+	r, err := domain.NewTagMove(tag, parent)
+	if err != nil {
+		return nil, err
+	}
+	// 子孫のタグを移動した結果が不正になるなら、1件も書き換えずに検証エラーを返す
+	tags, err := u.taggedTags(ctx, r.From())
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Validate(tags, u.tagPolicy); err != nil {
+		return nil, err
+	}
+	return u.replaceTags(ctx, r, limit)
+}
+
+// タグをすべてのLeafから外す
+func (u *LeafUsecase) DeleteTag(ctx context.Context, tag string, limit int) (*TagOperationResult, error) {
+	r, err := domain.NewTagRemoval(tag)
+	if err != nil {
+		return nil, err
+	}
+	return u.replaceTags(ctx, r, limit)
+}
+
+// 対象のタグが付いたLeafを最大limit件書き換える（ゴミ箱のLeafも対象）
+// 書き換えたLeafは対象のタグが外れるので、繰り返し実行すれば続きから処理される
+func (u *LeafUsecase) replaceTags(ctx context.Context, r domain.TagReplacement, limit int) (*TagOperationResult, error) {
+	if limit <= 0 {
+		limit = DefaultTagBatchSize
+	}
+	result := &TagOperationResult{}
+	for _, trashed := range []bool{false, true} {
+		for _, tag := range r.From() {
+			for result.Processed < limit {
+				opts := domain.ListOptions{Tags: []string{tag}, Trashed: trashed, Limit: limit - result.Processed}
+				leaves, _, err := u.repo.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				changed := 0
+				for i := range leaves {
+					ok, err := u.retag(ctx, &leaves[i], r)
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						changed++
+					}
+				}
+				result.Processed += changed
+				// 読み込みに反映されていない書き換えが返ることがあるので、進まなければ次の呼び出しに任せる
+				if changed == 0 {
+					break
+				}
+			}
+		}
+	}
+	remaining, err := u.hasTagged(ctx, r.From())
+	if err != nil {
+		return nil, err
+	}
+	result.Done = !remaining
+	return result, nil
+}
+
+// 1件のLeafのタグを付け替えて保存する（既に付け替え済みならfalse）
+func (u *LeafUsecase) retag(ctx context.Context, leaf *domain.Leaf, r domain.TagReplacement) (bool, error) {
+	for attempt := 0; ; attempt++ {
+		changed, err := leaf.ReplaceTags(r)
+		if err != nil || !changed {
+			return false, err
+		}
+		err = u.repo.Update(ctx, leaf)
+		// 一覧を取得した後に削除されたLeafは対象外
+		if errors.Is(err, domain.ErrLeafNotFound) {
+			return false, nil
+		}
+		if !errors.Is(err, domain.ErrVersionConflict) || attempt >= maxRetagRetries {
+			return err == nil, err
+		}
+		// 他の更新と競合したら読み直してやり直す（削除済みなら対象外）
+		leaf, err = u.repo.Get(ctx, leaf.ID().String())
+		if errors.Is(err, domain.ErrLeafNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// いずれかのタグ（パターン）が付いたLeafに付いているタグをすべて返す（ゴミ箱のLeafを含む）
+func (u *LeafUsecase) taggedTags(ctx context.Context, patterns []string) ([]string, error) {
+	var tags []string
+	for _, trashed := range []bool{false, true} {
+		opts := domain.ListOptions{Tags: patterns, Trashed: trashed, Limit: DefaultTagBatchSize}
+		for {
+			leaves, next, err := u.repo.List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, leaf := range leaves {
+				for _, t := range leaf.Tags() {
+					tags = append(tags, t.String())
+				}
+			}
+			if next == "" {
+				break
+			}
+			opts.Cursor = next
+		}
+	}
+	return tags, nil
+}
+
+// いずれかのタグが付いたLeafが残っているか（ゴミ箱のLeafを含む）
+func (u *LeafUsecase) hasTagged(ctx context.Context, tags []string) (bool, error) {
+	for _, trashed := range []bool{false, true} {
+		for _, tag := range tags {
+			leaves, _, err := u.repo.List(ctx, domain.ListOptions{Tags: []string{tag}, Trashed: trashed, Limit: 1})
+			if err != nil {
+				return false, err
+			}
+			if len(leaves) > 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// 利用者の別名を反映したタグの正規化
+// Leafに付けるタグはすべてこれを通す
+func (u *LeafUsecase) TagNormalizer(ctx context.Context) (*domain.TagNormalizer, error) {
+	aliases, err := u.aliases.ListAliases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewTagNormalizer(u.tagPolicy, aliases), nil
+}
+
+// 利用者のタグの別名
+func (u *LeafUsecase) ListTagAliases(ctx context.Context) ([]domain.TagAlias, error) {
+	return u.aliases.ListAliases(ctx)
+}
+
+// タグの別名を登録する（既存のLeafのタグは変えない。そろえるにはMergeTagsを使う）
+func (u *LeafUsecase) PutTagAlias(ctx context.Context, alias, tag string) (*domain.TagAlias, error) {
+	a, err := domain.NewTagAlias(alias, tag)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.aliases.PutAlias(ctx, a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (u *LeafUsecase) DeleteTagAlias(ctx context.Context, alias string) error {
+	return u.aliases.DeleteAlias(ctx, alias)
+}
@@ -0,0 +1,60 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/memory"
+)
+
+// 指定したLeafをUpdateの直前に削除するリポジトリ（一覧の取得後に削除された状況）
+type deletingRepository struct {
+	domain.LeafRepository
+	deleteID string
+}
+
+func (r *deletingRepository) Update(ctx context.Context, update *domain.Leaf) error {
+	if update.ID().String() == r.deleteID {
+		if err := r.LeafRepository.Delete(ctx, r.deleteID); err != nil {
+			return err
+		}
+	}
+	return r.LeafRepository.Update(ctx, update)
+}
+
+func TestRenameTagSkipsDeletedLeaf(t *testing.T) {
+	ctx := testContext(t)
+	repo := &deletingRepository{LeafRepository: memory.NewLeafMemoryRepository()}
+	var ids []string
+	for _, url := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		leaf, err := domain.NewLeaf("", "", "", url, "", []string{"golang"}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved, err := repo.Put(ctx, leaf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, saved.ID().String())
+	}
+	repo.deleteID = ids[1]
+	u := NewLeafUsecase(repo, memory.NewTagAliasMemoryRepository(), domain.TagPolicy{}, nil, nil)
+
+	result, err := u.RenameTag(ctx, "golang", "go", 0)
+	if err != nil {
+		t.Fatalf("RenameTag: %v", err)
+	}
+	if result.Processed != 2 || !result.Done {
+		t.Errorf("result = %+v, want 2 processed and done", result)
+	}
+	for _, id := range []string{ids[0], ids[2]} {
+		leaf, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tags := leaf.Tags(); len(tags) != 1 || tags[0].String() != "go" {
+			t.Errorf("%s tags = %v, want [go]", id, tags)
+		}
+	}
+}
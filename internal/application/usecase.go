@@ -3,6 +3,7 @@ package application
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/umekikazuya/logleaf/internal/domain"
 )
@@ -10,76 +11,194 @@ import (
 // LeafUsecase provides application-level operations for managing Leaf entities.
 // It interacts with the LeafRepository to perform CRUD operations and other business logic.
 type LeafUsecase struct {
-	repo domain.LeafRepository
+	repo      domain.LeafRepository
+	aliases   domain.TagAliasRepository
+	tagPolicy domain.TagPolicy
+	enricher  *LeafEnricher // nilならページのメタデータを取得しない
+	search    domain.SearchIndex
 }
 
-func NewLeafUsecase(repo domain.LeafRepository) *LeafUsecase {
-	return &LeafUsecase{repo: repo}
+// repoはNewIndexedLeafRepositoryでsearchの索引を更新するリポジトリにしておくこと
+func NewLeafUsecase(repo domain.LeafRepository, aliases domain.TagAliasRepository, tagPolicy domain.TagPolicy, enricher *LeafEnricher, search domain.SearchIndex) *LeafUsecase {
+	return &LeafUsecase{repo: repo, aliases: aliases, tagPolicy: tagPolicy, enricher: enricher, search: search}
 }
 
-func (u *LeafUsecase) ListLeaves(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, error) {
+func (u *LeafUsecase) ListLeaves(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, string, error) {
+	return u.repo.List(ctx, opts)
+}
+
+// ゴミ箱のLeafだけを返す
+func (u *LeafUsecase) ListTrash(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, string, error) {
+	opts.Trashed = true
 	return u.repo.List(ctx, opts)
 }
 
 func (u *LeafUsecase) GetLeaf(ctx context.Context, id string) (*domain.Leaf, error) {
-	if id == "" {
-		return nil, errors.New("leaf ID cannot be empty")
+	if _, err := domain.NewLeafID(id); err != nil {
+		return nil, err
 	}
-	return u.repo.Get(ctx, id)
+	return u.getActive(ctx, id, AnyVersion)
 }
 
 func (u *LeafUsecase) AddLeaf(ctx context.Context, dto *LeafInputDTO) (*domain.Leaf, error) {
-	leaf, err := domain.NewLeaf(dto.Note, dto.URL, dto.Platform, dto.Tags, false)
+	normalizer, err := u.TagNormalizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := normalizer.NormalizeAll(dto.Tags)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := domain.NewLeaf(dto.Title, dto.Description, dto.Note, dto.URL, dto.Platform, tags, false)
 	if err != nil {
 		return nil, err
 	}
-	return u.repo.Put(ctx, leaf)
+	saved, err := u.repo.Put(ctx, leaf)
+	if err != nil {
+		return nil, u.describeDuplicate(ctx, err)
+	}
+	// ページのメタデータは登録の応答を返した後に取得して補う
+	if u.enricher != nil {
+		u.enricher.Enqueue(ctx, saved.ID().String())
+	}
+	return saved, nil
 }
 
-func (u *LeafUsecase) UpdateLeaf(ctx context.Context, update *LeafInputDTO) error {
+func (u *LeafUsecase) UpdateLeaf(ctx context.Context, update *LeafUpdateDTO) (*domain.Leaf, error) {
 	// 既存Leaf取得
-	leaf, err := u.repo.Get(ctx, update.ID)
+	leaf, err := u.getActive(ctx, update.ID, update.Version)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if leaf == nil {
-		return errors.New("更新対象のLeafが見つかりません")
+	normalizer, err := u.TagNormalizer(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// Noteの更新
-	if err := leaf.UpdateNote(update.Note); err != nil {
-		return err
+	// 省略された説明・メモは変更しない
+	description, note := leaf.Description(), leaf.Note()
+	if update.Description != nil {
+		description = *update.Description
 	}
-	// Platformの更新
-	if err := leaf.UpdatePlatform(update.Platform); err != nil {
-		return err
+	if update.Note != nil {
+		note = *update.Note
 	}
-	// タグ変換
-	tags := make([]domain.Tag, 0, len(update.Tags))
-	for _, v := range update.Tags {
-		t, err := domain.NewTag(v)
-		if err != nil {
-			return err
+	// 省略されたタグは変更しない（nilのままEditに渡す）
+	var tags []string
+	if update.Tags != nil {
+		if tags, err = normalizer.NormalizeAll(*update.Tags); err != nil {
+			return nil, err
 		}
-		tags = append(tags, t)
 	}
-	if err := leaf.UpdateTags(tags); err != nil {
+	// Title・Description・Note・Platform・タグをまとめて検証して更新
+	if err := leaf.Edit(update.Title, description, note, update.Platform, tags); err != nil {
+		return nil, err
+	}
+	// 取得時のバージョンを条件に保存（間に他の更新があればErrVersionConflict）
+	if err := u.repo.Update(ctx, leaf); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+func (u *LeafUsecase) ReadLeaf(ctx context.Context, id string, version int) (*domain.Leaf, error) {
+	return u.ChangeLeafStatus(ctx, id, domain.StatusRead.String(), version)
+}
+
+// 未読に戻す
+func (u *LeafUsecase) UnreadLeaf(ctx context.Context, id string, version int) (*domain.Leaf, error) {
+	return u.ChangeLeafStatus(ctx, id, domain.StatusUnread.String(), version)
+}
+
+// 読書状態を変更する（既にその状態なら何もしない）
+func (u *LeafUsecase) ChangeLeafStatus(ctx context.Context, id string, status string, version int) (*domain.Leaf, error) {
+	to, err := domain.ParseLeafStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := u.getActive(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	err = leaf.ChangeStatus(to, time.Now())
+	if errors.Is(err, domain.ErrStatusUnchanged) {
+		return leaf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.Update(ctx, leaf); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// ゴミ箱に移す（保持期間を過ぎるまではRestoreLeafで戻せる）
+func (u *LeafUsecase) DeleteLeaf(ctx context.Context, id string, version int) error {
+	leaf, err := u.getActive(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	if err := leaf.MoveToTrash(time.Now()); err != nil {
 		return err
 	}
 	return u.repo.Update(ctx, leaf)
 }
 
-func (u *LeafUsecase) ReadLeaf(ctx context.Context, id string) error {
+// ゴミ箱から戻す
+func (u *LeafUsecase) RestoreLeaf(ctx context.Context, id string, version int) (*domain.Leaf, error) {
+	leaf, err := u.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(leaf, version); err != nil {
+		return nil, err
+	}
+	if err := leaf.Restore(); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Update(ctx, leaf); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// ゴミ箱にないLeafを取得する（ゴミ箱のLeafは存在しないものとして扱う）
+func (u *LeafUsecase) getActive(ctx context.Context, id string, version int) (*domain.Leaf, error) {
 	leaf, err := u.repo.Get(ctx, id)
 	if err != nil {
+		return nil, err
+	}
+	if leaf.Trashed() {
+		return nil, domain.ErrLeafNotFound
+	}
+	if err := checkVersion(leaf, version); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// URLの重複が、ゴミ箱にあるLeafとの重複かを調べてエラーに含める
+// ゴミ箱のLeafもURLを使用中として扱うため、復元すればよいことを利用者に伝える
+func (u *LeafUsecase) describeDuplicate(ctx context.Context, err error) error {
+	var dupErr *domain.DuplicateURLError
+	if !errors.As(err, &dupErr) {
 		return err
 	}
-	if leaf == nil {
-		return errors.New("leaf not found")
+	existing, getErr := u.repo.Get(ctx, dupErr.ExistingID)
+	if getErr != nil {
+		return err
 	}
-	leaf.MarkAsRead()
-	return u.repo.Update(ctx, leaf)
+	return &domain.DuplicateURLError{ExistingID: dupErr.ExistingID, Trashed: existing.Trashed()}
 }
 
-func (u *LeafUsecase) DeleteLeaf(ctx context.Context, id string) error {
-	return u.repo.Delete(ctx, id)
+// クライアントがバージョンを指定しないこと（If-Matchなし）を表す
+// versionのない既存データのバージョンは0なので、0は指定なしに使わない
+const AnyVersion = -1
+
+// クライアントが前提とするバージョンと一致するか（AnyVersionは指定なし）
+func checkVersion(leaf *domain.Leaf, expected int) error {
+	if expected != AnyVersion && leaf.Version() != expected {
+		return domain.ErrPreconditionFailed
+	}
+	return nil
 }
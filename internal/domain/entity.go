@@ -1,22 +1,37 @@
 package domain
 
 import (
-	"errors"
-	"fmt"
+	"net/netip"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
 )
 
 // タグの最大数
 const MaxTagsPerLeaf = 10
 
+// 外部サービスから同期するLeafのプラットフォーム
+const PlatformQiita = "qiita"
+
 // ドメイン固有エラー
 var (
-	ErrTagLimitExceeded = errors.New("タグは" + strconv.Itoa(MaxTagsPerLeaf) + "個までです。")
-	ErrAlreadyRead      = errors.New("既読状態です。")
+	ErrTagLimitExceeded = invalid("tags", CodeTooMany, "タグは"+strconv.Itoa(MaxTagsPerLeaf)+"個までです。")
+	ErrLeafNotFound     = newError(ErrNotFound, "leaf_not_found", "Leafが見つかりません。")
+	// 読み込み後に他の更新が行われた（楽観ロックの競合）
+	ErrVersionConflict = newError(ErrConflict, "version_conflict", "他の更新と競合しました。再取得してからやり直してください。")
+	// クライアントが指定したバージョンが最新でない（If-Matchの不一致）
+	ErrPreconditionFailed = newError(ErrPrecondition, "precondition_failed", "Leafは指定されたバージョンから更新されています。")
+	ErrInvalidCursor      = invalid("cursor", CodeInvalidFormat, "カーソルが無効です。")
+	ErrUnsupportedSort    = invalid("sort", CodeUnsupported, "指定された並び替え項目には対応していません。")
+	ErrAlreadyTrashed     = newError(ErrConflict, "already_trashed", "Leafは既にゴミ箱にあります。")
+	ErrNotInTrash         = newError(ErrConflict, "not_in_trash", "Leafはゴミ箱にありません。")
 )
 
 // LeafID Value Object
@@ -28,7 +43,7 @@ type LeafID struct {
 
 func NewLeafID(value string) (LeafID, error) {
 	if value == "" {
-		return LeafID{}, errors.New("LeafIDは空にできません")
+		return LeafID{}, invalid("id", CodeRequired, "LeafIDは空にできません")
 	}
 	return LeafID{value: value}, nil
 }
@@ -46,43 +61,122 @@ func (id LeafID) Equals(other LeafID) bool {
 }
 
 // URL Value Object
+// 入力されたURLをそのまま保持し、同一性の判定には正規化したURLを使う
 
 type LeafURL struct {
-	value string
+	value     string
+	canonical string
 }
 
+// 登録できるのはhttp・httpsの絶対URLだけ
+// 認証情報を含むURLと、ループバック・プライベート・リンクローカルのアドレスを直接指すURLは登録できない
+// （名前解決した結果の検査は、ページを取得する側で行う）
 func NewLeafURL(value string) (LeafURL, error) {
 	if value == "" {
-		return LeafURL{}, errors.New("URLは空にできません")
+		return LeafURL{}, invalid("url", CodeRequired, "URLは空にできません")
 	}
 	// URLバリデーション
 	if len(value) < 3 || len(value) > 2048 {
-		return LeafURL{}, errors.New("URLは3文字以上2048文字以下である必要があります")
+		return LeafURL{}, invalid("url", CodeTooLong, "URLは3文字以上2048文字以下である必要があります")
 	}
 	// URL形式の検証
-	if _, err := url.Parse(value); err != nil {
-		return LeafURL{}, fmt.Errorf("URLの形式が無効です: %w", err)
+	u, err := url.Parse(value)
+	if err != nil {
+		return LeafURL{}, invalid("url", CodeInvalidFormat, "URLの形式が無効です: "+err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return LeafURL{}, invalid("url", CodeUnsupported, "URLはhttpかhttpsで始まる必要があります")
+	}
+	if u.Host == "" || u.Hostname() == "" {
+		return LeafURL{}, invalid("url", CodeInvalidFormat, "URLにホスト名がありません")
+	}
+	if u.User != nil {
+		return LeafURL{}, invalid("url", CodeInvalidFormat, "URLにユーザー名やパスワードを含めることはできません")
+	}
+	if isInternalHost(u.Hostname()) {
+		return LeafURL{}, invalid("url", CodeUnsupported, "内部ネットワークのアドレスは登録できません")
+	}
+	return LeafURL{value: value, canonical: CanonicalizeURL(value)}, nil
+}
+
+// 保存済みのURLを復元する（検証の規則が厳しくなる前のURLもそのまま扱う）
+func restoreLeafURL(value string) (LeafURL, error) {
+	if value == "" {
+		return LeafURL{}, invalid("url", CodeRequired, "URLは空にできません")
+	}
+	return LeafURL{value: value, canonical: CanonicalizeURL(value)}, nil
+}
+
+// localhostと、ループバック・プライベート・リンクローカル・未指定のIPアドレスのホスト名か
+func isInternalHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
 	}
-	return LeafURL{value: value}, nil
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified()
 }
 
+// 入力されたURL
 func (u LeafURL) String() string {
 	return u.value
 }
 
+// 正規化したURL（重複の判定に使う）
+func (u LeafURL) Canonical() string {
+	return u.canonical
+}
+
+// 正規化したURLが一致すれば同じURL
 func (u LeafURL) Equals(other LeafURL) bool {
-	return u.value == other.value
+	return u.canonical == other.canonical
 }
 
 // Tag Value Object
+// /区切りの階層を持てる（例: aws/lambda の親は aws）
 
 type Tag struct {
 	value string
 }
 
+// どの設定でも超えられないタグの最大文字数（正規化後）
+// 利用者が付けるタグの上限はTagPolicyで設定する
+const TagLengthLimit = 256
+
+// 入力されたタグを正規化して検証する
+// 前後の空白を除き、NFKCで全角・半角の揺れをそろえ、連続する空白を1つにまとめる
+// 階層の区切り（/）の前後の空白も除く
+// 大文字・小文字と別名、設定した長さと文字の制限はTagNormalizerで扱う
 func NewTag(value string) (Tag, error) {
+	segments := strings.Split(norm.NFKC.String(value), TagSeparator)
+	for i, segment := range segments {
+		segments[i] = strings.Join(strings.Fields(segment), " ")
+	}
+	value = strings.Join(segments, TagSeparator)
 	if value == "" {
-		return Tag{}, errors.New("Tagは空にできません")
+		return Tag{}, invalid("tags", CodeRequired, "Tagは空にできません")
+	}
+	if utf8.RuneCountInString(value) > TagLengthLimit {
+		return Tag{}, invalid("tags", CodeTooLong, "Tagは"+strconv.Itoa(TagLengthLimit)+"文字以下である必要があります")
+	}
+	if strings.IndexFunc(value, func(r rune) bool { return !unicode.IsGraphic(r) }) >= 0 {
+		return Tag{}, invalid("tags", CodeInvalidFormat, "Tagに使えない文字が含まれています")
+	}
+	// 空の階層と、絞り込みのパターン（aws/*）と紛らわしい*だけの階層は使えない
+	if slices.Contains(segments, "") || slices.Contains(segments, tagWildcard) {
+		return Tag{}, invalid("tags", CodeInvalidFormat, "Tagの階層の指定が正しくありません")
+	}
+	return Tag{value: value}, nil
+}
+
+// 保存済みのタグを復元する（正規化の規則が変わる前のタグもそのまま扱う）
+func restoreTag(value string) (Tag, error) {
+	if value == "" {
+		return Tag{}, invalid("tags", CodeRequired, "Tagは空にできません")
 	}
 	return Tag{value: value}, nil
 }
@@ -100,150 +194,277 @@ func (t Tag) Equals(other Tag) bool {
 // タグ重複禁止・長さ制限も追加
 
 type Leaf struct {
-	id       LeafID
-	note     string
-	url      LeafURL
-	platform string
-	tags     []Tag
-	read     bool
-	syncedAt time.Time
+	id          LeafID
+	title       string // 記事のタイトル
+	description string // 記事の説明・抜粋（任意）
+	note        string // 利用者自身のメモ（Markdown、任意）
+	url         LeafURL
+	platform    string
+	tags        []Tag
+	reading     ReadingState
+	page        PageInfo  // URLのページから取得した情報
+	createdAt   time.Time // 登録した日時
+	updatedAt   time.Time // 最後に変更した日時（集約の変更のたびに更新する）
+	syncedAt    time.Time // 外部サービス（Qiita）から同期した日時（同期していなければゼロ値）
+	deletedAt   time.Time // ゴミ箱に移した日時（ゴミ箱になければゼロ値）
+	version     int       // 永続化済みのバージョン（未保存は0）
 }
 
 // Getter
-func (l *Leaf) ID() LeafID          { return l.id }
-func (l *Leaf) Note() string        { return l.note }
-func (l *Leaf) URL() LeafURL        { return l.url }
-func (l *Leaf) Platform() string    { return l.platform }
-func (l *Leaf) Tags() []Tag         { return l.tags }
-func (l *Leaf) Read() bool          { return l.read }
-func (l *Leaf) SyncedAt() time.Time { return l.syncedAt }
+func (l *Leaf) ID() LeafID           { return l.id }
+func (l *Leaf) Title() string        { return l.title }
+func (l *Leaf) Description() string  { return l.description }
+func (l *Leaf) Note() string         { return l.note }
+func (l *Leaf) URL() LeafURL         { return l.url }
+func (l *Leaf) Platform() string     { return l.platform }
+func (l *Leaf) Tags() []Tag          { return l.tags }
+func (l *Leaf) Read() bool           { return l.reading.Status.Done() } // 読み終えたか（読了・アーカイブ済み）
+func (l *Leaf) CreatedAt() time.Time { return l.createdAt }
+func (l *Leaf) UpdatedAt() time.Time { return l.updatedAt }
+func (l *Leaf) SyncedAt() time.Time  { return l.syncedAt }
+func (l *Leaf) Version() int         { return l.version }
+
+// 読書状態と遷移した日時
+func (l *Leaf) Status() LeafStatus         { return l.reading.Status }
+func (l *Leaf) ReadingState() ReadingState { return l.reading }
+
+// ゴミ箱に移した日時（ゴミ箱になければゼロ値）
+func (l *Leaf) DeletedAt() time.Time { return l.deletedAt }
+func (l *Leaf) Trashed() bool        { return !l.deletedAt.IsZero() }
+
+// 複製（タグのスライスも独立させ、元のLeafに影響しないコピーを返す）
+func (l *Leaf) Clone() *Leaf {
+	c := *l
+	c.tags = make([]Tag, len(l.tags))
+	copy(c.tags, l.tags)
+	return &c
+}
 
 // ファクトリ
 // ID生成
 // バリデーション一括
-func NewLeaf(note string, url string, platform string, tagValues []string, read bool) (*Leaf, error) {
-	if note == "" {
-		return nil, errors.New("Noteは空にできません")
+// タイトルが未指定ならURLを仮のタイトルにする（ページのメタデータを取得したら置き換える）
+// プラットフォームが未指定ならURLのホスト名から判定する
+func NewLeaf(title string, description string, note string, url string, platform string, tagValues []string, read bool) (*Leaf, error) {
+	if title == "" {
+		title = url
 	}
 	if platform == "" {
-		return nil, errors.New("Platformは空にできません")
+		platform = PlatformFromURL(url)
 	}
-	// IDが空の場合は新規生成
-	id := NewLeafIDFromUUID()
-	leafURL, err := NewLeafURL(url)
+	leafURL, tags, err := validateContent(title, url, platform, tagValues, NewLeafURL, NewTag)
 	if err != nil {
 		return nil, err
 	}
-	tags := make([]Tag, 0, len(tagValues))
-	tagSet := make(map[string]struct{})
-	for _, v := range tagValues {
-		t, err := NewTag(v)
-		if err != nil {
-			return nil, err
-		}
-		if _, exists := tagSet[t.value]; exists {
-			return nil, errors.New("タグが重複しています")
-		}
-		tagSet[t.value] = struct{}{}
-		tags = append(tags, t)
-	}
-	if len(tags) > MaxTagsPerLeaf {
-		return nil, ErrTagLimitExceeded
-	}
+	now := time.Now().UTC()
+	// IDが空の場合は新規生成
 	return &Leaf{
-		id:       id,
-		note:     note,
-		url:      leafURL,
-		platform: platform,
-		tags:     tags,
-		read:     false,
-		syncedAt: time.Now().UTC(),
+		id:          NewLeafIDFromUUID(),
+		title:       title,
+		description: description,
+		note:        note,
+		url:         leafURL,
+		platform:    platform,
+		tags:        tags,
+		reading:     ReadingState{Status: StatusUnread},
+		createdAt:   now,
+		updatedAt:   now,
 	}, nil
 }
 
 // 既存のLeafを再構築するためのファクトリ
-func ReconstructLeaf(id string, note string, url string, platform string, tagValues []string, read bool, syncedAt time.Time) (*Leaf, error) {
+func ReconstructLeaf(id string, title string, description string, note string, url string, platform string, tagValues []string, reading ReadingState, page PageInfo, createdAt time.Time, updatedAt time.Time, syncedAt time.Time, deletedAt time.Time, version int) (*Leaf, error) {
+	var v validator
 	leafID, err := NewLeafID(id)
-	if err != nil {
+	v.add("", err)
+	leafURL, tags, err := validateContent(title, url, platform, tagValues, restoreLeafURL, restoreTag)
+	v.add("", err)
+	v.add("", reading.validate())
+	if err := v.err(); err != nil {
 		return nil, err
 	}
-	if note == "" {
-		return nil, errors.New("Noteは空にできません")
-	}
-	if platform == "" {
-		return nil, errors.New("Platformは空にできません")
-	}
-	leafURL, err := NewLeafURL(url)
-	if err != nil {
-		return nil, err
+	return &Leaf{
+		id:          leafID,
+		title:       title,
+		description: description,
+		note:        note,
+		url:         leafURL,
+		platform:    platform,
+		tags:        tags,
+		reading:     reading,
+		page:        page,
+		createdAt:   createdAt,
+		updatedAt:   updatedAt,
+		syncedAt:    syncedAt,
+		deletedAt:   deletedAt,
+		version:     version,
+	}, nil
+}
+
+// Leafの内容をまとめて検証する（不正な項目はすべて報告する）
+// URLはnewURLで、タグはnewTagで変換する
+func validateContent(title string, url string, platform string, tagValues []string, newURL func(string) (LeafURL, error), newTag func(string) (Tag, error)) (LeafURL, []Tag, error) {
+	var v validator
+	checkTitle(&v, title)
+	leafURL, err := newURL(url)
+	v.add("url", err)
+	checkPlatform(&v, platform)
+	tags := parseTags(&v, tagValues, newTag)
+	if err := v.err(); err != nil {
+		return LeafURL{}, nil, err
 	}
+	return leafURL, tags, nil
+}
+
+// タグ文字列を変換し、重複と個数も検証する（項目名は入力での位置）
+func parseTags(v *validator, tagValues []string, newTag func(string) (Tag, error)) []Tag {
 	tags := make([]Tag, 0, len(tagValues))
-	tagSet := make(map[string]struct{})
-	for _, v := range tagValues {
-		t, err := NewTag(v)
+	seen := make(map[string]struct{}, len(tagValues))
+	for i, value := range tagValues {
+		t, err := newTag(value)
 		if err != nil {
-			return nil, err
+			v.add(tagField(i), err)
+			continue
 		}
-		if _, exists := tagSet[t.value]; exists {
-			return nil, errors.New("タグが重複しています")
+		if _, exists := seen[t.value]; exists {
+			v.add(tagField(i), invalid("tags", CodeDuplicate, "タグが重複しています"))
+			continue
 		}
-		tagSet[t.value] = struct{}{}
+		seen[t.value] = struct{}{}
 		tags = append(tags, t)
 	}
+	if len(tagValues) > MaxTagsPerLeaf {
+		v.add("", ErrTagLimitExceeded)
+	}
+	return tags
+}
+
+func checkTitle(v *validator, title string) {
+	if title == "" {
+		v.add("title", invalid("title", CodeRequired, "Titleは空にできません"))
+	}
+}
+
+func checkPlatform(v *validator, platform string) {
+	if platform == "" {
+		v.add("platform", invalid("platform", CodeRequired, "Platformは空にできません"))
+	}
+}
+
+// タグの重複（2件目以降を報告）と個数の上限
+func checkTags(v *validator, tags []Tag) {
+	seen := make(map[string]struct{}, len(tags))
+	for i, t := range tags {
+		if _, exists := seen[t.value]; exists {
+			v.add(tagField(i), invalid("tags", CodeDuplicate, "タグが重複しています"))
+		}
+		seen[t.value] = struct{}{}
+	}
 	if len(tags) > MaxTagsPerLeaf {
-		return nil, ErrTagLimitExceeded
+		v.add("", ErrTagLimitExceeded)
 	}
-	return &Leaf{
-		id:       leafID,
-		note:     note,
-		url:      leafURL,
-		platform: platform,
-		tags:     tags,
-		read:     read,
-		syncedAt: syncedAt,
-	}, nil
 }
 
-// ノート内容の変更
-func (l *Leaf) UpdateNote(note string) error {
-	if note == "" {
-		return errors.New("Noteは空にできません")
+func tagField(i int) string {
+	return "tags[" + strconv.Itoa(i) + "]"
+}
+
+// 永続化に成功したときにリポジトリが呼び出し、保存したバージョンに進める
+func (l *Leaf) IncrementVersion() {
+	l.version++
+}
+
+// 変更した日時を記録する（集約を変更するメソッドは必ず呼ぶ）
+func (l *Leaf) touch(now time.Time) {
+	l.updatedAt = now.UTC()
+}
+
+// 外部サービスから同期した日時を記録する
+func (l *Leaf) MarkSynced(now time.Time) {
+	l.syncedAt = now.UTC()
+	l.touch(now)
+}
+
+// 内容の編集（すべて検証してから、まとめて反映する）
+// tagValuesがnilならタグは変更しない（空のスライスならすべて外す）
+func (l *Leaf) Edit(title string, description string, note string, platform string, tagValues []string) error {
+	var v validator
+	checkTitle(&v, title)
+	checkPlatform(&v, platform)
+	tags := l.tags
+	if tagValues != nil {
+		tags = parseTags(&v, tagValues, NewTag)
 	}
+	if err := v.err(); err != nil {
+		return err
+	}
+	l.title = title
+	l.description = description
 	l.note = note
+	l.platform = platform
+	l.tags = tags
+	l.touch(time.Now())
+	return nil
+}
+
+// タイトルの変更
+func (l *Leaf) UpdateTitle(title string) error {
+	var v validator
+	checkTitle(&v, title)
+	if err := v.err(); err != nil {
+		return err
+	}
+	l.title = title
+	l.touch(time.Now())
 	return nil
 }
 
+// メモの変更（空にしてもよい）
+func (l *Leaf) UpdateNote(note string) {
+	l.note = note
+	l.touch(time.Now())
+}
+
 // プラットフォームの変更
 func (l *Leaf) UpdatePlatform(platform string) error {
-	if platform == "" {
-		return errors.New("Platformは空にできません")
+	var v validator
+	checkPlatform(&v, platform)
+	if err := v.err(); err != nil {
+		return err
 	}
 	l.platform = platform
+	l.touch(time.Now())
 	return nil
 }
 
-// 既読マーク
-func (l *Leaf) MarkAsRead() error {
-	if l.read {
-		return ErrAlreadyRead
+// ゴミ箱に移す
+func (l *Leaf) MoveToTrash(now time.Time) error {
+	if l.Trashed() {
+		return ErrAlreadyTrashed
 	}
-	l.read = true
+	l.deletedAt = now.UTC()
+	l.touch(now)
+	return nil
+}
+
+// ゴミ箱から戻す
+func (l *Leaf) Restore() error {
+	if !l.Trashed() {
+		return ErrNotInTrash
+	}
+	l.deletedAt = time.Time{}
+	l.touch(time.Now())
 	return nil
 }
 
 // タグのバリデーション付き更新（重複・上限チェック）
 func (l *Leaf) UpdateTags(tags []Tag) error {
-	if len(tags) > MaxTagsPerLeaf {
-		return ErrTagLimitExceeded
-	}
-	tagSet := make(map[string]struct{})
-	for _, t := range tags {
-		if _, exists := tagSet[t.value]; exists {
-			return errors.New("タグが重複しています")
-		}
-		tagSet[t.value] = struct{}{}
+	var v validator
+	checkTags(&v, tags)
+	if err := v.err(); err != nil {
+		return err
 	}
-	l.tags = make([]Tag, len(tags))
-	copy(l.tags, tags)
+	l.tags = slices.Clone(tags)
+	l.touch(time.Now())
 	return nil
 }
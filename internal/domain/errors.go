@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// エラーの種類
+// 個々のドメインエラーはいずれかの種類に分類され、errors.Isで判定できる
+var (
+	ErrNotFound   = errors.New("見つかりません。")
+	ErrValidation = errors.New("入力内容が正しくありません。")
+	ErrConflict   = errors.New("現在の状態と競合しています。")
+	// クライアントが前提とした状態（If-Matchのバージョンなど）と一致しない
+	ErrPrecondition = errors.New("前提条件を満たしていません。")
+)
+
+// 検証エラーのコード
+const (
+	CodeRequired      = "required"       // 必須項目が空
+	CodeTooLong       = "too_long"       // 長さの上限を超えている
+	CodeTooMany       = "too_many"       // 個数の上限を超えている
+	CodeInvalidFormat = "invalid_format" // 形式が正しくない
+	CodeDuplicate     = "duplicate"      // 重複している
+	CodeUnsupported   = "unsupported"    // 対応していない値
+	CodeOutOfRange    = "out_of_range"   // 数値が範囲外
+)
+
+// 種類に分類されたドメインエラー（メッセージは種類ごとではなく個別）
+type kindError struct {
+	kind error
+	code string
+	msg  string
+}
+
+func newError(kind error, code, msg string) error {
+	return &kindError{kind: kind, code: code, msg: msg}
+}
+
+func (e *kindError) Error() string     { return e.msg }
+func (e *kindError) Unwrap() error     { return e.kind }
+func (e *kindError) ErrorCode() string { return e.code }
+
+// 入力値の検証エラー
+type ValidationError struct {
+	Field   string // 不正な項目（例: note, tags[3]。特定できなければ空）
+	Code    string // Code*のいずれか
+	Message string
+}
+
+func (e *ValidationError) Error() string     { return e.Message }
+func (e *ValidationError) Unwrap() error     { return ErrValidation }
+func (e *ValidationError) ErrorCode() string { return "validation_failed" }
+
+func invalid(field, code, message string) *ValidationError {
+	return &ValidationError{Field: field, Code: code, Message: message}
+}
+
+// 項目名を付け替えた検証エラー（値オブジェクトのエラーを集約側の項目名で報告する）
+func (e *ValidationError) at(field string) *ValidationError {
+	return &ValidationError{Field: field, Code: e.Code, Message: e.Message}
+}
+
+// まとめて報告する複数の検証エラー
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, v := range e {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, " ")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, v := range e {
+		errs[i] = v
+	}
+	return errs
+}
+
+func (e ValidationErrors) ErrorCode() string { return "validation_failed" }
+
+// 検証エラーを集める（1件もなければnil）
+type validator struct {
+	errs ValidationErrors
+}
+
+// errを集める（fieldがあれば項目名を付け替える、検証エラー以外は形式エラーとして扱う）
+func (v *validator) add(field string, err error) {
+	if err == nil {
+		return
+	}
+	var many ValidationErrors
+	if errors.As(err, &many) {
+		v.errs = append(v.errs, many...)
+		return
+	}
+	one, ok := err.(*ValidationError)
+	if !ok && !errors.As(err, &one) {
+		one = invalid(field, CodeInvalidFormat, err.Error())
+	}
+	if field != "" {
+		one = one.at(field)
+	}
+	v.errs = append(v.errs, one)
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// 同じURLのLeafが既に登録されている
+type DuplicateURLError struct {
+	ExistingID string // 登録済みLeafのID
+	Trashed    bool   // 登録済みLeafがゴミ箱にある（復元すれば使える）
+}
+
+func (e *DuplicateURLError) Error() string {
+	if e.Trashed {
+		return "同じURLのLeafがゴミ箱にあります。復元して使ってください。(ID: " + e.ExistingID + ")"
+	}
+	return "同じURLのLeafが既に登録されています。(ID: " + e.ExistingID + ")"
+}
+
+func (e *DuplicateURLError) Unwrap() error     { return ErrConflict }
+func (e *DuplicateURLError) ErrorCode() string { return "duplicate_url" }
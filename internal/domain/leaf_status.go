@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidStatusTransition = newError(ErrConflict, "invalid_status_transition", "現在の状態からは変更できません。")
+	ErrStatusUnchanged         = newError(ErrConflict, "status_unchanged", "既にその状態です。")
+)
+
+// Leafの読書状態
+type LeafStatus string
+
+const (
+	StatusUnread   LeafStatus = "unread"   // 未読
+	StatusReading  LeafStatus = "reading"  // 読んでいる途中
+	StatusRead     LeafStatus = "read"     // 読了
+	StatusArchived LeafStatus = "archived" // アーカイブ済み（読み終えて整理した、または読まないと決めた）
+)
+
+// 状態の順序（この順で進み、未読に戻すとやり直しになる）
+var leafStatuses = []LeafStatus{StatusUnread, StatusReading, StatusRead, StatusArchived}
+
+// 遷移できる状態
+// アーカイブ済みから読んでいる途中には戻さない（読み直すなら未読に戻す）
+var statusTransitions = map[LeafStatus][]LeafStatus{
+	StatusUnread:   {StatusReading, StatusRead, StatusArchived},
+	StatusReading:  {StatusUnread, StatusRead, StatusArchived},
+	StatusRead:     {StatusUnread, StatusReading, StatusArchived},
+	StatusArchived: {StatusUnread, StatusRead},
+}
+
+func ParseLeafStatus(value string) (LeafStatus, error) {
+	s := LeafStatus(value)
+	if !slices.Contains(leafStatuses, s) {
+		return "", invalid("status", CodeUnsupported, "状態はunread, reading, read, archivedのいずれかです")
+	}
+	return s, nil
+}
+
+func (s LeafStatus) String() string { return string(s) }
+
+// 読み終えた状態か（読了・アーカイブ済み）
+// 未読の件数や既読の絞り込みはこれで判定する
+func (s LeafStatus) Done() bool {
+	return s == StatusRead || s == StatusArchived
+}
+
+// 読書状態と、各状態に最後に遷移した日時（その状態を経ていなければゼロ値）
+type ReadingState struct {
+	Status     LeafStatus
+	StartedAt  time.Time // 読み始めた日時
+	ReadAt     time.Time // 読み終えた日時
+	ArchivedAt time.Time // アーカイブした日時
+}
+
+// 既読の真偽値だけを持つ既存データの読書状態
+func ReadingStateFromRead(read bool) ReadingState {
+	if read {
+		return ReadingState{Status: StatusRead}
+	}
+	return ReadingState{Status: StatusUnread}
+}
+
+func (s ReadingState) validate() error {
+	if _, err := ParseLeafStatus(string(s.Status)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 状態をtoに遷移させる
+// toの日時をnowにし、toより後の状態の日時は消す（未読に戻すとすべて消える）
+func (s ReadingState) transition(to LeafStatus, now time.Time) (ReadingState, error) {
+	if _, err := ParseLeafStatus(string(to)); err != nil {
+		return s, err
+	}
+	if s.Status == to {
+		return s, ErrStatusUnchanged
+	}
+	if !slices.Contains(statusTransitions[s.Status], to) {
+		return s, ErrInvalidStatusTransition
+	}
+	next := ReadingState{Status: to}
+	now = now.UTC()
+	for _, status := range leafStatuses[1:] {
+		var at time.Time
+		switch {
+		case status == to:
+			at = now
+		case slices.Index(leafStatuses, status) < slices.Index(leafStatuses, to):
+			at = s.at(status)
+		}
+		next.set(status, at)
+	}
+	return next, nil
+}
+
+func (s ReadingState) at(status LeafStatus) time.Time {
+	switch status {
+	case StatusReading:
+		return s.StartedAt
+	case StatusRead:
+		return s.ReadAt
+	case StatusArchived:
+		return s.ArchivedAt
+	}
+	return time.Time{}
+}
+
+func (s *ReadingState) set(status LeafStatus, at time.Time) {
+	switch status {
+	case StatusReading:
+		s.StartedAt = at
+	case StatusRead:
+		s.ReadAt = at
+	case StatusArchived:
+		s.ArchivedAt = at
+	}
+}
+
+// 読書状態を変更する（同じ状態ならErrStatusUnchanged、遷移できなければErrInvalidStatusTransition）
+func (l *Leaf) ChangeStatus(to LeafStatus, now time.Time) error {
+	next, err := l.reading.transition(to, now)
+	if err != nil {
+		return err
+	}
+	l.reading = next
+	l.touch(now)
+	return nil
+}
+
+// 既読にする
+func (l *Leaf) MarkAsRead(now time.Time) error {
+	return l.ChangeStatus(StatusRead, now)
+}
+
+// 未読に戻す
+func (l *Leaf) MarkAsUnread(now time.Time) error {
+	return l.ChangeStatus(StatusUnread, now)
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// プラットフォームが未指定のときに使う、一般のWebページのプラットフォーム
+const PlatformWeb = "web"
+
+// ホスト名から判定できるプラットフォーム
+var platformHosts = map[string]string{
+	"qiita.com": PlatformQiita,
+	"zenn.dev":  "zenn",
+}
+
+// URLのホスト名からプラットフォームを判定する（判定できなければPlatformWeb）
+func PlatformFromURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return PlatformWeb
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if platform, ok := platformHosts[host]; ok {
+		return platform
+	}
+	return PlatformWeb
+}
+
+// Webページから取り出したメタデータ（取り出せなかった項目は空）
+type PageMetadata struct {
+	Title        string
+	Description  string
+	SiteName     string
+	CanonicalURL string
+	ImageURL     string
+	PublishedAt  time.Time
+}
+
+// LeafのURLのページについて保存しておく情報（取得していなければゼロ値）
+type PageInfo struct {
+	SiteName     string
+	CanonicalURL string // ページが示す正規のURL
+	ImageURL     string // og:imageなどのサムネイル画像
+	PublishedAt  time.Time
+	FetchedAt    time.Time // メタデータを取得した日時
+}
+
+// Webページのメタデータを取得する
+// 取得・解析できなければエラーを返す
+type PageMetadataFetcher interface {
+	Fetch(ctx context.Context, url string) (*PageMetadata, error)
+}
+
+// ページの情報（サイト名・正規のURL・画像・公開日時とメタデータを取得した日時）
+func (l *Leaf) Page() PageInfo { return l.page }
+
+// メタデータを取得済みか
+func (l *Leaf) Enriched() bool { return !l.page.FetchedAt.IsZero() }
+
+// 取得したメタデータで、まだ値のない項目を補う
+// タイトルは登録時にURLを仮に入れたままのときだけ置き換え、利用者が入力した値は上書きしない
+func (l *Leaf) Enrich(meta PageMetadata, now time.Time) {
+	if meta.Title != "" && (l.title == "" || l.title == l.url.String()) {
+		l.title = meta.Title
+	}
+	if l.description == "" {
+		l.description = meta.Description
+	}
+	if l.page.SiteName == "" {
+		l.page.SiteName = meta.SiteName
+	}
+	if l.page.CanonicalURL == "" {
+		l.page.CanonicalURL = meta.CanonicalURL
+	}
+	if l.page.ImageURL == "" {
+		l.page.ImageURL = meta.ImageURL
+	}
+	if l.page.PublishedAt.IsZero() {
+		l.page.PublishedAt = meta.PublishedAt.UTC()
+	}
+	l.page.FetchedAt = now.UTC()
+	l.touch(now)
+}
@@ -2,22 +2,150 @@ package domain
 
 import (
 	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// タグ条件の結合方法
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any" // いずれかのタグを含む
+	TagMatchAll TagMatch = "all" // すべてのタグを含む
+)
+
+// 並び替えに使える項目
+const (
+	SortBySyncedAt = "synced_at"
+	SortByTitle    = "title"
+	SortByRead     = "read"
+)
+
+// SortByが対応している並び替え項目か（空はID順として扱う）
+func IsSupportedSort(sortBy string) bool {
+	switch sortBy {
+	case "", SortBySyncedAt, SortByTitle, SortByRead:
+		return true
+	}
+	return false
+}
+
+// 並び替えキーとして使うTitleの最大バイト数
+const maxTitleSortKeyBytes = 256
+
+// SortByで指定された項目の並び替えキーを返す
+// 文字列の辞書順がそのまま並び順になるよう整形する（未対応の項目は空文字）
+func LeafSortKey(l *Leaf, sortBy string) string {
+	// 同期していないLeafは登録日時で並べる
+	syncedAt := l.SyncedAt()
+	if syncedAt.IsZero() {
+		syncedAt = l.CreatedAt()
+	}
+	synced := syncedAt.UTC().Format(time.RFC3339)
+	switch sortBy {
+	case SortBySyncedAt:
+		return synced
+	case SortByTitle:
+		// 制御文字は並び順に使わない
+		key := strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, strings.ToLower(l.Title()))
+		if len(key) > maxTitleSortKeyBytes {
+			key = key[:maxTitleSortKeyBytes]
+			for !utf8.ValidString(key) {
+				key = key[:len(key)-1]
+			}
+		}
+		return key
+	case SortByRead:
+		// 未読→既読の順、同じ状態の中では同期日時順
+		if l.Read() {
+			return "1#" + synced
+		}
+		return "0#" + synced
+	}
+	return ""
+}
+
 type ListOptions struct {
-	Platforms []string
-	Tags      []string
-	ReadOnly  bool
+	Platforms []string     // いずれかに一致（空なら絞り込みなし）
+	Tags      []string     // タグ、またはaws/*のような子孫のタグのパターン
+	TagMatch  TagMatch     // Tagsの結合方法（未指定はTagMatchAny）
+	Read      *bool        // nilなら既読状態で絞り込まない
+	Statuses  []LeafStatus // 読書状態のいずれかに一致（空なら絞り込みなし）
 	Limit     int
-	Offset    int
-	SortBy    string
+	Cursor    string // 前ページのListが返した続きのカーソル（空なら先頭から）
+	SortBy    string // SortBy*のいずれか（空ならID順）
 	SortDesc  bool
+	Trashed   bool // trueならゴミ箱のLeafだけ、falseならゴミ箱以外のLeafだけ
+}
+
+// Leafが絞り込み条件（Platforms, Tags, Read, Statuses, Trashed）を満たすか判定する
+// DBの検索機能を使えない実装向け
+func (o ListOptions) Match(l *Leaf) bool {
+	if l.Trashed() != o.Trashed {
+		return false
+	}
+	if len(o.Platforms) > 0 && !slices.Contains(o.Platforms, l.Platform()) {
+		return false
+	}
+	if len(o.Tags) > 0 {
+		hits := 0
+		for _, want := range o.Tags {
+			if slices.ContainsFunc(l.Tags(), func(t Tag) bool { return MatchTagPattern(want, t) }) {
+				hits++
+			}
+		}
+		if hits == 0 || (o.TagMatch == TagMatchAll && hits < len(o.Tags)) {
+			return false
+		}
+	}
+	if o.Read != nil && l.Read() != *o.Read {
+		return false
+	}
+	if len(o.Statuses) > 0 && !slices.Contains(o.Statuses, l.Status()) {
+		return false
+	}
+	return true
+}
+
+// タグごとのLeafの件数（ゴミ箱のLeafは数えない）
+type TagCount struct {
+	Tag    string
+	Leaves int // タグが付いたLeafの数
+	Unread int // うち未読のLeafの数
 }
 
+// LeafRepositoryの実装はrepotest.TestLeafRepositoryの仕様を満たすこと
+// 存在しないIDへのGet/Update/DeleteはErrLeafNotFoundを返す
+// 書き込みはLeaf.Version()+1を保存し、成功したらLeaf.IncrementVersion()を呼ぶ
+// ゴミ箱のLeafもGet/FindByURLで返し、URLの一意性の対象にする
+// URLの一意性はLeafURL.Canonical()で判定し、FindByURL/LookupURLsも正規化したURLで照合する
+// ゴミ箱のLeafは実装に設定された保持期間を過ぎると完全に削除される（削除されるまでの時間は実装による）
 type LeafRepository interface {
 	Get(ctx context.Context, id string) (*Leaf, error)
-	List(ctx context.Context, opts ListOptions) ([]Leaf, error)
+	// 続きがある場合は次ページのカーソルを返す（最終ページなら空文字）
+	List(ctx context.Context, opts ListOptions) ([]Leaf, string, error)
+	// URLが一致するLeafを返す（なければErrLeafNotFound）
+	FindByURL(ctx context.Context, url string) (*Leaf, error)
+	// 登録済みのURLとLeafIDの対応を返す（キーは渡されたURLのまま。未登録のURLは含まない）
+	LookupURLs(ctx context.Context, urls []string) (map[string]string, error)
+	// 新規作成。同じIDが既にあればErrVersionConflict、同じURLがあれば*DuplicateURLError
 	Put(ctx context.Context, leaf *Leaf) (*Leaf, error)
+	// 一括作成（取り込み用）。保存したLeafを返す
+	// IDかURLが登録済みのLeaf、一括内でURLが重複する2件目以降は保存せずに読み飛ばす
+	// 実装によっては確認と書き込みがアトミックでなく、同時に作成された同じURLは重複しうる
+	PutMany(ctx context.Context, leaves []*Leaf) ([]*Leaf, error)
+	// 保存済みのバージョンがupdate.Version()と異なればErrVersionConflict
+	// 変更後のURLが他のLeafと重複すれば*DuplicateURLError
 	Update(ctx context.Context, update *Leaf) error
 	Delete(ctx context.Context, id string) error
+	// 利用者が使っているタグと件数をタグ名順に返す（ゴミ箱のLeafにだけ付いたタグは含まない）
+	ListTags(ctx context.Context) ([]TagCount, error)
 }
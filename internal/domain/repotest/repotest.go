@@ -0,0 +1,1031 @@
+// LeafRepositoryの実装が満たすべき振る舞いのテスト
+// 各実装のテストから次のように実行する
+//
+//	func TestLeafRepository(t *testing.T) {
+//		repotest.TestLeafRepository(t, func(t *testing.T) domain.LeafRepository {
+//			return memory.NewLeafMemoryRepository()
+//		})
+//	}
+package repotest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// 空のリポジトリを返す（サブテストごとに呼ぶ）
+type Factory func(t *testing.T) domain.LeafRepository
+
+// newRepoで作ったリポジトリがLeafRepositoryの振る舞いを満たすか検証する
+func TestLeafRepository(t *testing.T, newRepo Factory) {
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newRepo(t)) })
+	t.Run("RoundTrip", func(t *testing.T) { testRoundTrip(t, newRepo(t)) })
+	t.Run("UpdateAndDelete", func(t *testing.T) { testUpdateAndDelete(t, newRepo(t)) })
+	t.Run("Filter", func(t *testing.T) { testFilter(t, newRepo(t)) })
+	t.Run("Sort", func(t *testing.T) { testSort(t, newRepo(t)) })
+	t.Run("Pagination", func(t *testing.T) { testPagination(t, newRepo(t)) })
+	t.Run("InvalidOptions", func(t *testing.T) { testInvalidOptions(t, newRepo(t)) })
+	t.Run("Versioning", func(t *testing.T) { testVersioning(t, newRepo(t)) })
+	t.Run("ConcurrentUpdates", func(t *testing.T) { testConcurrentUpdates(t, newRepo(t)) })
+	t.Run("UserIsolation", func(t *testing.T) { testUserIsolation(t, newRepo(t)) })
+	t.Run("URLUniqueness", func(t *testing.T) { testURLUniqueness(t, newRepo(t)) })
+	t.Run("CanonicalURL", func(t *testing.T) { testCanonicalURL(t, newRepo(t)) })
+	t.Run("PutMany", func(t *testing.T) { testPutMany(t, newRepo(t)) })
+	t.Run("Trash", func(t *testing.T) { testTrash(t, newRepo(t)) })
+	t.Run("TagCounts", func(t *testing.T) { testTagCounts(t, newRepo(t)) })
+	t.Run("TagHierarchy", func(t *testing.T) { testTagHierarchy(t, newRepo(t)) })
+	t.Run("ReadingStatus", func(t *testing.T) { testReadingStatus(t, newRepo(t)) })
+}
+
+// 存在しないIDへのGet/DeleteはErrLeafNotFound
+func testNotFound(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	leaf, err := repo.Get(ctx, "missing")
+	if !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrLeafNotFound", err)
+	}
+	if leaf != nil {
+		t.Errorf("Get(missing) leaf = %v, want nil", leaf)
+	}
+	if err := repo.Delete(ctx, "missing"); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("Delete(missing) error = %v, want ErrLeafNotFound", err)
+	}
+}
+
+// 全フィールドが保存・復元される（日時は秒精度・UTC）
+func testRoundTrip(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	synced := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	want, err := domain.ReconstructLeaf("id-1", "タイトル", "記事の説明", "# メモ\n\n- 要点", "https://qiita.com/a/items/1", "qiita",
+		[]string{"go", "aws", "DynamoDB"}, domain.ReadingStateFromRead(true),
+		domain.PageInfo{SiteName: "Qiita", CanonicalURL: "https://qiita.com/a/items/1", ImageURL: "https://qiita.com/a/items/1.png", PublishedAt: synced.Add(-24 * time.Hour), FetchedAt: synced},
+		synced, synced.Add(time.Hour), synced, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("ReconstructLeaf: %v", err)
+	}
+	if _, err := repo.Put(ctx, want); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := repo.Get(ctx, "id-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertLeafEqual(t, got, want)
+
+	// 一覧でも同じ内容が返る
+	leaves, _, err := repo.List(ctx, domain.ListOptions{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(leaves) != 1 {
+		t.Fatalf("List returned %d leaves, want 1", len(leaves))
+	}
+	assertLeafEqual(t, &leaves[0], want)
+}
+
+func testUpdateAndDelete(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	leaf := mustLeaf(t, "id-1", "before", "https://example.com/1", "web", []string{"a"}, false, baseTime)
+	if _, err := repo.Put(ctx, leaf); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	stored, err := repo.Get(ctx, "id-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	mustDo(t, stored.UpdateTitle("after"))
+	stored.UpdateNote("## memo")
+	mustDo(t, stored.UpdatePlatform("qiita"))
+	mustDo(t, stored.UpdateTags(mustTags(t, "b", "c")))
+	mustDo(t, stored.ChangeStatus(domain.StatusReading, baseTime.Add(time.Hour)))
+	mustDo(t, stored.MarkAsRead(baseTime.Add(2*time.Hour)))
+	stored.Enrich(domain.PageMetadata{Description: "説明", SiteName: "Example", ImageURL: "https://example.com/1.png"}, baseTime.Add(3*time.Hour))
+	if err := repo.Update(ctx, stored); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := repo.Get(ctx, "id-1")
+	if err != nil {
+		t.Fatalf("Get after Update: %v", err)
+	}
+	assertLeafEqual(t, got, stored)
+
+	// 取得したLeafを変更してもリポジトリの内容は変わらない
+	mustDo(t, got.UpdateTitle("local change"))
+	again, err := repo.Get(ctx, "id-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if again.Title() != "after" {
+		t.Errorf("Title = %q after mutating a returned leaf, want %q", again.Title(), "after")
+	}
+
+	if err := repo.Delete(ctx, "id-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.Get(ctx, "id-1"); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrLeafNotFound", err)
+	}
+}
+
+func testFilter(t *testing.T, repo domain.LeafRepository) {
+	seed(t, repo, fixtures(t))
+
+	yes, no := true, false
+	cases := []struct {
+		name string
+		opts domain.ListOptions
+		want []string
+	}{
+		{"none", domain.ListOptions{}, []string{"l1", "l2", "l3", "l4", "l5"}},
+		{"platform", domain.ListOptions{Platforms: []string{"qiita"}}, []string{"l1", "l3", "l5"}},
+		{"platforms", domain.ListOptions{Platforms: []string{"zenn", "web"}}, []string{"l2", "l4"}},
+		{"tag", domain.ListOptions{Tags: []string{"aws"}}, []string{"l2", "l3"}},
+		{"tags any", domain.ListOptions{Tags: []string{"aws", "rust"}}, []string{"l2", "l3", "l4"}},
+		{"tags all", domain.ListOptions{Tags: []string{"go", "aws"}, TagMatch: domain.TagMatchAll}, []string{"l3"}},
+		{"tags all duplicated", domain.ListOptions{Tags: []string{"go", "go"}, TagMatch: domain.TagMatchAll}, []string{"l1", "l3", "l5"}},
+		{"tag missing", domain.ListOptions{Tags: []string{"java"}}, nil},
+		{"read", domain.ListOptions{Read: &yes}, []string{"l2", "l5"}},
+		{"unread", domain.ListOptions{Read: &no}, []string{"l1", "l3", "l4"}},
+		{"combined", domain.ListOptions{Platforms: []string{"qiita"}, Tags: []string{"go"}, Read: &no}, []string{"l1", "l3"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := collectIDs(t, repo, tc.opts)
+			slices.Sort(got)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("List(%+v) = %v, want %v", tc.opts, got, tc.want)
+			}
+		})
+	}
+}
+
+// 並び順はLeafSortKey→IDの順（SortByが空ならID順）
+func testSort(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	leaves := fixtures(t)
+	seed(t, repo, leaves)
+
+	for _, sortBy := range []string{"", domain.SortBySyncedAt, domain.SortByTitle, domain.SortByRead} {
+		for _, desc := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s desc=%v", sortBy, desc), func(t *testing.T) {
+				want := expectedOrder(leaves, sortBy, desc)
+				got, _, err := repo.List(ctx, domain.ListOptions{SortBy: sortBy, SortDesc: desc})
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				if ids := leafIDs(got); !slices.Equal(ids, want) {
+					t.Errorf("order = %v, want %v", ids, want)
+				}
+			})
+		}
+	}
+}
+
+// カーソルをたどると全件を重複なく、並び順どおりに取得できる
+func testPagination(t *testing.T, repo domain.LeafRepository) {
+	leaves := fixtures(t)
+	seed(t, repo, leaves)
+
+	for _, sortBy := range []string{"", domain.SortBySyncedAt, domain.SortByTitle, domain.SortByRead} {
+		for _, limit := range []int{1, 2, 5, 10} {
+			t.Run(fmt.Sprintf("%s limit=%d", sortBy, limit), func(t *testing.T) {
+				opts := domain.ListOptions{SortBy: sortBy, SortDesc: true, Limit: limit}
+				got := collectIDs(t, repo, opts)
+				if want := expectedOrder(leaves, sortBy, true); !slices.Equal(got, want) {
+					t.Errorf("paged order = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+
+	t.Run("with filter", func(t *testing.T) {
+		opts := domain.ListOptions{Platforms: []string{"qiita"}, SortBy: domain.SortByTitle, Limit: 1}
+		got := collectIDs(t, repo, opts)
+		var filtered []*domain.Leaf
+		for _, l := range leaves {
+			if l.Platform() == "qiita" {
+				filtered = append(filtered, l)
+			}
+		}
+		if want := expectedOrder(filtered, domain.SortByTitle, false); !slices.Equal(got, want) {
+			t.Errorf("paged order = %v, want %v", got, want)
+		}
+	})
+}
+
+func testInvalidOptions(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	seed(t, repo, fixtures(t))
+
+	if _, _, err := repo.List(ctx, domain.ListOptions{SortBy: "unknown"}); !errors.Is(err, domain.ErrUnsupportedSort) {
+		t.Errorf("List(SortBy: unknown) error = %v, want ErrUnsupportedSort", err)
+	}
+	if _, _, err := repo.List(ctx, domain.ListOptions{Cursor: "not a cursor"}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("List(Cursor: garbage) error = %v, want ErrInvalidCursor", err)
+	}
+	// 別の並び替えで発行されたカーソルは使えない
+	_, next, err := repo.List(ctx, domain.ListOptions{SortBy: domain.SortByTitle, Limit: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if next == "" {
+		t.Fatal("List(Limit: 1) returned no cursor")
+	}
+	if _, _, err := repo.List(ctx, domain.ListOptions{SortBy: domain.SortBySyncedAt, Cursor: next}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("List with cursor from another sort error = %v, want ErrInvalidCursor", err)
+	}
+	// 逆順のリクエストでも使えない
+	if _, _, err := repo.List(ctx, domain.ListOptions{SortBy: domain.SortByTitle, SortDesc: true, Cursor: next}); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("List with cursor from another order error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+// 書き込みごとにバージョンが進み、古いバージョンからの書き込みは拒否される
+func testVersioning(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	leaf := mustLeaf(t, "id-1", "note", "https://example.com/1", "web", nil, false, baseTime)
+	if _, err := repo.Put(ctx, leaf); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if leaf.Version() != 1 {
+		t.Errorf("Version after Put = %d, want 1", leaf.Version())
+	}
+	// 同じIDでの作成は上書きしない
+	dup := mustLeaf(t, "id-1", "other", "https://example.com/2", "web", nil, false, baseTime)
+	if _, err := repo.Put(ctx, dup); !errors.Is(err, domain.ErrVersionConflict) {
+		t.Errorf("Put(existing id) error = %v, want ErrVersionConflict", err)
+	}
+
+	first, _ := repo.Get(ctx, "id-1")
+	second, _ := repo.Get(ctx, "id-1")
+	mustDo(t, first.UpdateTitle("first"))
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if first.Version() != 2 {
+		t.Errorf("Version after Update = %d, want 2", first.Version())
+	}
+	mustDo(t, second.UpdateTitle("second"))
+	if err := repo.Update(ctx, second); !errors.Is(err, domain.ErrVersionConflict) {
+		t.Errorf("Update(stale) error = %v, want ErrVersionConflict", err)
+	}
+	if second.Version() != 1 {
+		t.Errorf("Version after failed Update = %d, want unchanged 1", second.Version())
+	}
+	got, err := repo.Get(ctx, "id-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertLeafEqual(t, got, first)
+
+	missing := mustLeaf(t, "missing", "note", "https://example.com/3", "web", nil, false, baseTime)
+	if err := repo.Update(ctx, missing); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("Update(missing) error = %v, want ErrLeafNotFound", err)
+	}
+}
+
+// 並行した書き込みでデータ破損が起きず、競合した更新はErrVersionConflictになる
+func testConcurrentUpdates(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	shared := mustLeaf(t, "shared", "note", "https://example.com/shared", "web", nil, false, baseTime)
+	if _, err := repo.Put(ctx, shared); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	var updated atomic.Int32
+	errs := make(chan error, workers*2)
+	for i := range workers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			id := fmt.Sprintf("w%d", i)
+			leaf, err := domain.ReconstructLeaf(id, "title", "", "", "https://example.com/"+id, "web", nil, domain.ReadingStateFromRead(false), domain.PageInfo{}, baseTime, baseTime, time.Time{}, time.Time{}, 0)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if _, err := repo.Put(ctx, leaf); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			leaf, err := repo.Get(ctx, "shared")
+			if err != nil {
+				errs <- err
+				return
+			}
+			tag, err := domain.NewTag(fmt.Sprintf("tag%d", i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := leaf.UpdateTags([]domain.Tag{tag}); err != nil {
+				errs <- err
+				return
+			}
+			err = repo.Update(ctx, leaf)
+			if err == nil {
+				updated.Add(1)
+			} else if !errors.Is(err, domain.ErrVersionConflict) {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent write: %v", err)
+	}
+
+	if got := collectIDs(t, repo, domain.ListOptions{Limit: 3}); len(got) != workers+1 {
+		t.Errorf("List returned %d leaves, want %d", len(got), workers+1)
+	}
+	got, err := repo.Get(ctx, "shared")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if tags := got.Tags(); len(tags) != 1 {
+		t.Errorf("shared tags = %v, want exactly one tag from a single writer", tags)
+	}
+	if updated.Load() == 0 {
+		t.Error("no concurrent Update succeeded")
+	}
+	if want := 1 + int(updated.Load()); got.Version() != want {
+		t.Errorf("Version = %d after %d successful updates, want %d", got.Version(), updated.Load(), want)
+	}
+}
+
+// 利用者ごとにデータが分離され、他の利用者のLeafは存在しないものとして扱われる
+func testUserIsolation(t *testing.T, repo domain.LeafRepository) {
+	alice, bob := userContext("alice"), userContext("bob")
+	leaf := mustLeaf(t, "shared-id", "alice's", "https://example.com/a", "web", []string{"go"}, false, baseTime)
+	if _, err := repo.Put(alice, leaf); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if _, err := repo.Get(bob, "shared-id"); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("Get from another user error = %v, want ErrLeafNotFound", err)
+	}
+	if got := collectIDsAs(t, bob, repo, domain.ListOptions{}); len(got) != 0 {
+		t.Errorf("List from another user = %v, want empty", got)
+	}
+	if err := repo.Delete(bob, "shared-id"); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("Delete from another user error = %v, want ErrLeafNotFound", err)
+	}
+	// 他の利用者の書き込みは元の利用者のLeafを変更しない
+	hijack := mustLeaf(t, "shared-id", "bob's", "https://example.com/b", "web", nil, false, baseTime)
+	_, _ = repo.Put(bob, hijack)
+	_ = repo.Update(bob, hijack)
+	got, err := repo.Get(alice, "shared-id")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertLeafEqual(t, got, leaf)
+
+	// 利用者が特定できないコンテキストは拒否する
+	anonymous := context.Background()
+	if _, err := repo.Get(anonymous, "shared-id"); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("Get without user error = %v, want ErrUnauthenticated", err)
+	}
+	if _, _, err := repo.List(anonymous, domain.ListOptions{}); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("List without user error = %v, want ErrUnauthenticated", err)
+	}
+	if _, err := repo.Put(anonymous, hijack); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("Put without user error = %v, want ErrUnauthenticated", err)
+	}
+	if err := repo.Update(anonymous, hijack); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("Update without user error = %v, want ErrUnauthenticated", err)
+	}
+	if err := repo.Delete(anonymous, "shared-id"); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("Delete without user error = %v, want ErrUnauthenticated", err)
+	}
+}
+
+// 利用者を設定したコンテキスト
+func testURLUniqueness(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	leaves := fixtures(t)
+	seed(t, repo, leaves)
+	l1, l2 := leaves[0], leaves[1]
+
+	// 同じURLは別のIDでも登録できない
+	dup := mustLeaf(t, "dup", "dup", l1.URL().String(), "web", nil, false, baseTime)
+	_, err := repo.Put(ctx, dup)
+	var dupErr *domain.DuplicateURLError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("Put duplicate URL error = %v, want *DuplicateURLError", err)
+	}
+	if dupErr.ExistingID != l1.ID().String() {
+		t.Errorf("ExistingID = %q, want %q", dupErr.ExistingID, l1.ID().String())
+	}
+	if _, err := repo.Get(ctx, "dup"); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("Get rejected leaf error = %v, want ErrLeafNotFound", err)
+	}
+
+	// URLで検索できる
+	got, err := repo.FindByURL(ctx, l1.URL().String())
+	if err != nil {
+		t.Fatalf("FindByURL: %v", err)
+	}
+	want, err := repo.Get(ctx, l1.ID().String())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertLeafEqual(t, got, want)
+	if _, err := repo.FindByURL(ctx, "https://example.com/missing"); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("FindByURL missing error = %v, want ErrLeafNotFound", err)
+	}
+	found, err := repo.LookupURLs(ctx, []string{l1.URL().String(), l2.URL().String(), l1.URL().String(), "https://example.com/missing"})
+	if err != nil {
+		t.Fatalf("LookupURLs: %v", err)
+	}
+	wantURLs := map[string]string{l1.URL().String(): l1.ID().String(), l2.URL().String(): l2.ID().String()}
+	if !maps.Equal(found, wantURLs) {
+		t.Errorf("LookupURLs = %v, want %v", found, wantURLs)
+	}
+
+	// 保存済みのl2のURLだけを変えたLeaf
+	relocate := func(url string) *domain.Leaf {
+		t.Helper()
+		stored, err := repo.Get(ctx, l2.ID().String())
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		leaf, err := domain.ReconstructLeaf(stored.ID().String(), stored.Title(), stored.Description(), stored.Note(), url, stored.Platform(), nil, stored.ReadingState(), stored.Page(), stored.CreatedAt(), stored.UpdatedAt(), stored.SyncedAt(), stored.DeletedAt(), stored.Version())
+		if err != nil {
+			t.Fatalf("ReconstructLeaf: %v", err)
+		}
+		return leaf
+	}
+	// 他のLeafのURLへは変更できない
+	if err := repo.Update(ctx, relocate(l1.URL().String())); !errors.As(err, &dupErr) || dupErr.ExistingID != l1.ID().String() {
+		t.Errorf("Update to duplicate URL error = %v, want *DuplicateURLError for %s", err, l1.ID())
+	}
+	// URLを変更すると、変更前のURLは空き、変更後のURLで検索できる
+	mustDo(t, repo.Update(ctx, relocate("https://example.com/moved")))
+	if got, err := repo.FindByURL(ctx, "https://example.com/moved"); err != nil || got.ID() != l2.ID() {
+		t.Errorf("FindByURL moved = %v, %v, want %s", got, err, l2.ID())
+	}
+	if _, err := repo.FindByURL(ctx, l2.URL().String()); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("FindByURL old URL error = %v, want ErrLeafNotFound", err)
+	}
+
+	// 削除するとURLを再登録できる
+	mustDo(t, repo.Delete(ctx, l1.ID().String()))
+	if _, err := repo.Put(ctx, dup); err != nil {
+		t.Fatalf("Put after delete: %v", err)
+	}
+	if got, err := repo.FindByURL(ctx, l1.URL().String()); err != nil || got.ID().String() != "dup" {
+		t.Errorf("FindByURL after re-register = %v, %v, want dup", got, err)
+	}
+
+	// URLの一意性は利用者ごと
+	other := mustLeaf(t, "other", "bob's", l1.URL().String(), "web", nil, false, baseTime)
+	if _, err := repo.Put(userContext("bob"), other); err != nil {
+		t.Errorf("Put same URL for another user: %v", err)
+	}
+	if _, err := repo.FindByURL(userContext("carol"), l1.URL().String()); !errors.Is(err, domain.ErrLeafNotFound) {
+		t.Errorf("FindByURL from another user error = %v, want ErrLeafNotFound", err)
+	}
+}
+
+// URLの一意性は正規化したURLで判定し、入力されたURLはそのまま保存する
+func testCanonicalURL(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	original := "https://qiita.com/a/items/abc?utm_source=twitter"
+	leaf := mustLeaf(t, "id-1", "title", original, "qiita", nil, false, baseTime)
+	if _, err := repo.Put(ctx, leaf); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// 正規化すると同じになるURLは登録できない
+	var dupErr *domain.DuplicateURLError
+	dup := mustLeaf(t, "dup", "dup", "https://www.qiita.com/a/items/abc/", "qiita", nil, false, baseTime)
+	if _, err := repo.Put(ctx, dup); !errors.As(err, &dupErr) || dupErr.ExistingID != "id-1" {
+		t.Fatalf("Put canonical duplicate error = %v, want *DuplicateURLError for id-1", err)
+	}
+	if stored, err := repo.PutMany(ctx, []*domain.Leaf{dup}); err != nil || len(stored) != 0 {
+		t.Errorf("PutMany canonical duplicate = %d leaves, %v, want none", len(stored), err)
+	}
+
+	// 正規化する前のどの表記でも検索でき、入力されたURLが返る
+	got, err := repo.FindByURL(ctx, "https://Qiita.com/a/items/abc#comments")
+	if err != nil {
+		t.Fatalf("FindByURL: %v", err)
+	}
+	if got.ID().String() != "id-1" || got.URL().String() != original {
+		t.Errorf("FindByURL = %s %q, want id-1 %q", got.ID(), got.URL(), original)
+	}
+	variants := []string{"https://qiita.com/a/items/abc", "http://qiita.com/a/items/abc/?fbclid=x", "https://example.com/missing"}
+	found, err := repo.LookupURLs(ctx, variants)
+	if err != nil {
+		t.Fatalf("LookupURLs: %v", err)
+	}
+	wantURLs := map[string]string{variants[0]: "id-1", variants[1]: "id-1"}
+	if !maps.Equal(found, wantURLs) {
+		t.Errorf("LookupURLs = %v, want %v", found, wantURLs)
+	}
+
+	// 表記だけを変える更新は重複にならず、索引も引き続き使える
+	relocated, err := domain.ReconstructLeaf("id-1", got.Title(), "", "", "https://qiita.com/a/items/abc", "qiita", nil,
+		got.ReadingState(), got.Page(), got.CreatedAt(), got.UpdatedAt(), got.SyncedAt(), got.DeletedAt(), got.Version())
+	if err != nil {
+		t.Fatalf("ReconstructLeaf: %v", err)
+	}
+	if err := repo.Update(ctx, relocated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, err := repo.FindByURL(ctx, original); err != nil || got.URL().String() != "https://qiita.com/a/items/abc" {
+		t.Errorf("FindByURL after Update = %v, %v, want the updated URL", got, err)
+	}
+	// 削除すると正規化したURLも空く
+	if err := repo.Delete(ctx, "id-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.Put(ctx, dup); err != nil {
+		t.Errorf("Put after Delete: %v", err)
+	}
+}
+
+// 一括作成は登録済みのIDとURL、一括内で重複するURLを読み飛ばす
+func testPutMany(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	leaves := fixtures(t)
+	seed(t, repo, leaves[:1])
+
+	// 書き込みの分割（25件ずつなど）をまたぐ件数にする
+	var batch []*domain.Leaf
+	for i := range 60 {
+		batch = append(batch, mustLeaf(t, fmt.Sprintf("bulk-%02d", i), "bulk", fmt.Sprintf("https://example.com/bulk/%d", i), "web", []string{"go"}, false, baseTime.Add(time.Duration(i)*time.Minute)))
+	}
+	batch = append(batch,
+		mustLeaf(t, "dup-url", "dup", leaves[0].URL().String(), "web", nil, false, baseTime),
+		mustLeaf(t, leaves[0].ID().String(), "dup", "https://example.com/dup-id", "web", nil, false, baseTime),
+		mustLeaf(t, "dup-in-batch", "dup", "https://example.com/bulk/0", "web", nil, false, baseTime),
+	)
+	stored, err := repo.PutMany(ctx, batch)
+	if err != nil {
+		t.Fatalf("PutMany: %v", err)
+	}
+	if len(stored) != 60 {
+		t.Fatalf("PutMany stored %d leaves, want 60", len(stored))
+	}
+	for i, leaf := range stored {
+		if want := fmt.Sprintf("bulk-%02d", i); leaf.ID().String() != want {
+			t.Fatalf("stored[%d] = %s, want %s", i, leaf.ID(), want)
+		}
+		if leaf.Version() != 1 {
+			t.Errorf("stored[%d] Version = %d, want 1", i, leaf.Version())
+		}
+	}
+
+	got, err := repo.Get(ctx, "bulk-42")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertLeafEqual(t, got, stored[42])
+	for _, id := range []string{"dup-url", "dup-in-batch"} {
+		if _, err := repo.Get(ctx, id); !errors.Is(err, domain.ErrLeafNotFound) {
+			t.Errorf("Get(%s) error = %v, want ErrLeafNotFound", id, err)
+		}
+	}
+	if got, err := repo.FindByURL(ctx, "https://example.com/bulk/0"); err != nil || got.ID().String() != "bulk-00" {
+		t.Errorf("FindByURL = %v, %v, want bulk-00", got, err)
+	}
+	if ids := collectIDs(t, repo, domain.ListOptions{}); len(ids) != 61 {
+		t.Errorf("List returned %d leaves, want 61", len(ids))
+	}
+
+	// 空の一括作成は何もしない
+	if stored, err := repo.PutMany(ctx, nil); err != nil || len(stored) != 0 {
+		t.Errorf("PutMany(nil) = %v, %v, want empty", stored, err)
+	}
+}
+
+// ゴミ箱のLeafは通常の一覧に含まれず、Trashedの一覧にだけ含まれる
+func testTrash(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	leaves := fixtures(t)
+	seed(t, repo, leaves)
+
+	trashed, err := repo.Get(ctx, "l3")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	mustDo(t, trashed.MoveToTrash(baseTime.Add(24*time.Hour)))
+	mustDo(t, repo.Update(ctx, trashed))
+
+	got, err := repo.Get(ctx, "l3")
+	if err != nil {
+		t.Fatalf("Get trashed: %v", err)
+	}
+	assertLeafEqual(t, got, trashed)
+	if !got.Trashed() {
+		t.Errorf("Trashed = false after MoveToTrash")
+	}
+
+	active := expectedOrder(slices.DeleteFunc(slices.Clone(leaves), func(l *domain.Leaf) bool { return l.ID().String() == "l3" }), domain.SortBySyncedAt, false)
+	opts := domain.ListOptions{SortBy: domain.SortBySyncedAt, Limit: 2}
+	if got := collectIDs(t, repo, opts); !slices.Equal(got, active) {
+		t.Errorf("List = %v, want %v", got, active)
+	}
+	opts.Trashed = true
+	if got := collectIDs(t, repo, opts); !slices.Equal(got, []string{"l3"}) {
+		t.Errorf("List(Trashed) = %v, want [l3]", got)
+	}
+	// ゴミ箱のLeafもURLを使い続ける
+	dup := mustLeaf(t, "dup", "dup", trashed.URL().String(), "web", nil, false, baseTime)
+	var dupErr *domain.DuplicateURLError
+	if _, err := repo.Put(ctx, dup); !errors.As(err, &dupErr) || dupErr.ExistingID != "l3" {
+		t.Errorf("Put URL of trashed leaf error = %v, want *DuplicateURLError for l3", err)
+	}
+
+	mustDo(t, got.Restore())
+	mustDo(t, repo.Update(ctx, got))
+	restored, err := repo.Get(ctx, "l3")
+	if err != nil {
+		t.Fatalf("Get restored: %v", err)
+	}
+	assertLeafEqual(t, restored, got)
+	if got := collectIDs(t, repo, opts); len(got) != 0 {
+		t.Errorf("List(Trashed) after restore = %v, want empty", got)
+	}
+}
+
+// タグの件数は書き込みに追従し、ゴミ箱のLeafを数えない
+func testTagCounts(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	seed(t, repo, fixtures(t))
+	assertTagCounts(t, repo, []domain.TagCount{
+		{Tag: "aws", Leaves: 2, Unread: 1},
+		{Tag: "go", Leaves: 3, Unread: 2},
+		{Tag: "rust", Leaves: 1, Unread: 1},
+	})
+
+	update := func(id string, change func(l *domain.Leaf) error) {
+		t.Helper()
+		leaf, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", id, err)
+		}
+		mustDo(t, change(leaf))
+		mustDo(t, repo.Update(ctx, leaf))
+	}
+	update("l4", func(l *domain.Leaf) error { return l.UpdateTags(mustTags(t, "go")) })
+	update("l1", func(l *domain.Leaf) error { return l.MarkAsRead(baseTime) })
+	update("l3", func(l *domain.Leaf) error { return l.MoveToTrash(baseTime) })
+	mustDo(t, repo.Delete(ctx, "l2"))
+	assertTagCounts(t, repo, []domain.TagCount{
+		{Tag: "go", Leaves: 3, Unread: 1},
+	})
+	if got := collectIDs(t, repo, domain.ListOptions{Tags: []string{"go"}}); !slices.Equal(got, []string{"l1", "l4", "l5"}) {
+		t.Errorf("List(go) = %v, want [l1 l4 l5]", got)
+	}
+	if got := collectIDs(t, repo, domain.ListOptions{Tags: []string{"aws"}, Trashed: true}); !slices.Equal(got, []string{"l3"}) {
+		t.Errorf("List(aws, Trashed) = %v, want [l3]", got)
+	}
+
+	update("l3", func(l *domain.Leaf) error { return l.Restore() })
+	assertTagCounts(t, repo, []domain.TagCount{
+		{Tag: "aws", Leaves: 1, Unread: 1},
+		{Tag: "go", Leaves: 4, Unread: 2},
+	})
+	// 他の利用者のタグは数えない
+	if counts, err := repo.ListTags(userContext("bob")); err != nil || len(counts) != 0 {
+		t.Errorf("ListTags(bob) = %v, %v, want empty", counts, err)
+	}
+}
+
+// 読書状態で絞り込め、既読の絞り込みとタグの未読件数は読み終えた状態（読了・アーカイブ済み）を既読として扱う
+func testReadingStatus(t *testing.T, repo domain.LeafRepository) {
+	ctx := userContext("alice")
+	seed(t, repo, fixtures(t))
+	change := func(id string, status domain.LeafStatus) {
+		t.Helper()
+		leaf, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", id, err)
+		}
+		mustDo(t, leaf.ChangeStatus(status, baseTime))
+		mustDo(t, repo.Update(ctx, leaf))
+	}
+	change("l1", domain.StatusReading)
+	change("l2", domain.StatusArchived)
+	change("l4", domain.StatusArchived)
+	change("l5", domain.StatusUnread)
+
+	yes := true
+	tests := []struct {
+		name string
+		opts domain.ListOptions
+		want []string
+	}{
+		{"reading", domain.ListOptions{Statuses: []domain.LeafStatus{domain.StatusReading}}, []string{"l1"}},
+		{"archived", domain.ListOptions{Statuses: []domain.LeafStatus{domain.StatusArchived}}, []string{"l2", "l4"}},
+		{"unread or reading", domain.ListOptions{Statuses: []domain.LeafStatus{domain.StatusUnread, domain.StatusReading}}, []string{"l1", "l3", "l5"}},
+		{"read flag", domain.ListOptions{Read: &yes}, []string{"l2", "l4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collectIDs(t, repo, tt.opts); !slices.Equal(got, tt.want) {
+				t.Errorf("List = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	assertTagCounts(t, repo, []domain.TagCount{
+		{Tag: "aws", Leaves: 2, Unread: 1},
+		{Tag: "go", Leaves: 3, Unread: 3},
+		{Tag: "rust", Leaves: 1, Unread: 0},
+	})
+	got, err := repo.Get(ctx, "l2")
+	if err != nil {
+		t.Fatalf("Get(l2): %v", err)
+	}
+	if state := got.ReadingState(); state.Status != domain.StatusArchived || !state.ArchivedAt.Equal(baseTime) {
+		t.Errorf("ReadingState(l2) = %+v, want archived at %v", state, baseTime)
+	}
+}
+
+// 子孫のパターン（aws/*）は子孫のタグにだけ一致する（aws自身や、awsで始まる別のタグは含まない）
+func testTagHierarchy(t *testing.T, repo domain.LeafRepository) {
+	seed(t, repo, []*domain.Leaf{
+		mustLeaf(t, "h1", "a", "https://example.com/h1", "web", []string{"aws"}, false, baseTime),
+		mustLeaf(t, "h2", "b", "https://example.com/h2", "web", []string{"aws/lambda", "go"}, false, baseTime),
+		mustLeaf(t, "h3", "c", "https://example.com/h3", "web", []string{"aws/lambda/edge"}, false, baseTime),
+		mustLeaf(t, "h4", "d", "https://example.com/h4", "web", []string{"awsx/s3", "aws.x/y"}, false, baseTime),
+		mustLeaf(t, "h5", "e", "https://example.com/h5", "web", []string{"gcp/run", "go"}, false, baseTime),
+	})
+	tests := []struct {
+		name string
+		opts domain.ListOptions
+		want []string
+	}{
+		{"descendants", domain.ListOptions{Tags: []string{"aws/*"}}, []string{"h2", "h3"}},
+		{"nested descendants", domain.ListOptions{Tags: []string{"aws/lambda/*"}}, []string{"h3"}},
+		{"exact parent", domain.ListOptions{Tags: []string{"aws"}}, []string{"h1"}},
+		{"any", domain.ListOptions{Tags: []string{"aws/*", "gcp/*"}}, []string{"h2", "h3", "h5"}},
+		{"all", domain.ListOptions{Tags: []string{"go", "aws/*"}, TagMatch: domain.TagMatchAll}, []string{"h2"}},
+		{"no descendants", domain.ListOptions{Tags: []string{"go/*"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collectIDs(t, repo, tt.opts); !slices.Equal(got, tt.want) {
+				t.Errorf("List = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func assertTagCounts(t *testing.T, repo domain.LeafRepository, want []domain.TagCount) {
+	t.Helper()
+	got, err := repo.ListTags(userContext("alice"))
+	if err != nil {
+		t.Fatalf("ListTags: %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ListTags = %v, want %v", got, want)
+	}
+}
+
+func userContext(user string) context.Context {
+	id, err := domain.NewUserID(user)
+	if err != nil {
+		panic(err)
+	}
+	return domain.WithUserID(context.Background(), id)
+}
+
+var baseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// 絞り込み・並び替えの検証用データ
+func fixtures(t *testing.T) []*domain.Leaf {
+	t.Helper()
+	return []*domain.Leaf{
+		mustLeaf(t, "l1", "Banana", "https://qiita.com/x/items/1", "qiita", []string{"go"}, false, baseTime.Add(3*time.Hour)),
+		mustLeaf(t, "l2", "apple", "https://zenn.dev/x/articles/2", "zenn", []string{"aws"}, true, baseTime.Add(1*time.Hour)),
+		mustLeaf(t, "l3", "cherry", "https://qiita.com/x/items/3", "qiita", []string{"go", "aws"}, false, baseTime.Add(5*time.Hour)),
+		mustLeaf(t, "l4", "Apple", "https://example.com/4", "web", []string{"rust"}, false, baseTime.Add(2*time.Hour)),
+		mustLeaf(t, "l5", "date", "https://qiita.com/x/items/5", "qiita", []string{"go"}, true, baseTime.Add(4*time.Hour)),
+	}
+}
+
+func seed(t *testing.T, repo domain.LeafRepository, leaves []*domain.Leaf) {
+	t.Helper()
+	for _, l := range leaves {
+		if _, err := repo.Put(userContext("alice"), l.Clone()); err != nil {
+			t.Fatalf("Put(%s): %v", l.ID(), err)
+		}
+	}
+}
+
+// カーソルをたどってすべてのIDを取得する
+// 最終ページの前に空ページが返ることは許容する
+func collectIDs(t *testing.T, repo domain.LeafRepository, opts domain.ListOptions) []string {
+	t.Helper()
+	return collectIDsAs(t, userContext("alice"), repo, opts)
+}
+
+func collectIDsAs(t *testing.T, ctx context.Context, repo domain.LeafRepository, opts domain.ListOptions) []string {
+	t.Helper()
+	var ids []string
+	for page := 0; ; page++ {
+		if page > 100 {
+			t.Fatal("pagination did not terminate")
+		}
+		leaves, next, err := repo.List(ctx, opts)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", opts, err)
+		}
+		if opts.Limit > 0 && len(leaves) > opts.Limit {
+			t.Fatalf("List returned %d leaves, exceeding Limit %d", len(leaves), opts.Limit)
+		}
+		ids = append(ids, leafIDs(leaves)...)
+		if next == "" {
+			return ids
+		}
+		opts.Cursor = next
+	}
+}
+
+func expectedOrder(leaves []*domain.Leaf, sortBy string, desc bool) []string {
+	sorted := slices.Clone(leaves)
+	slices.SortFunc(sorted, func(a, b *domain.Leaf) int {
+		c := cmp.Or(
+			cmp.Compare(domain.LeafSortKey(a, sortBy), domain.LeafSortKey(b, sortBy)),
+			cmp.Compare(a.ID().String(), b.ID().String()),
+		)
+		if desc {
+			return -c
+		}
+		return c
+	})
+	ids := make([]string, len(sorted))
+	for i, l := range sorted {
+		ids[i] = l.ID().String()
+	}
+	return ids
+}
+
+func leafIDs(leaves []domain.Leaf) []string {
+	ids := make([]string, len(leaves))
+	for i := range leaves {
+		ids[i] = leaves[i].ID().String()
+	}
+	return ids
+}
+
+func assertLeafEqual(t *testing.T, got, want *domain.Leaf) {
+	t.Helper()
+	if !got.ID().Equals(want.ID()) {
+		t.Errorf("ID = %q, want %q", got.ID(), want.ID())
+	}
+	if got.Title() != want.Title() {
+		t.Errorf("Title = %q, want %q", got.Title(), want.Title())
+	}
+	if got.Description() != want.Description() {
+		t.Errorf("Description = %q, want %q", got.Description(), want.Description())
+	}
+	if got.Note() != want.Note() {
+		t.Errorf("Note = %q, want %q", got.Note(), want.Note())
+	}
+	if got.URL().String() != want.URL().String() {
+		t.Errorf("URL = %q, want %q", got.URL(), want.URL())
+	}
+	if got.Platform() != want.Platform() {
+		t.Errorf("Platform = %q, want %q", got.Platform(), want.Platform())
+	}
+	if got.Read() != want.Read() {
+		t.Errorf("Read = %v, want %v", got.Read(), want.Read())
+	}
+	gotState, wantState := got.ReadingState(), want.ReadingState()
+	if gotState.Status != wantState.Status {
+		t.Errorf("Status = %q, want %q", gotState.Status, wantState.Status)
+	}
+	if !sameSecond(gotState.StartedAt, wantState.StartedAt) ||
+		!sameSecond(gotState.ReadAt, wantState.ReadAt) ||
+		!sameSecond(gotState.ArchivedAt, wantState.ArchivedAt) {
+		t.Errorf("ReadingState = %+v, want %+v", gotState, wantState)
+	}
+	gotPage, wantPage := got.Page(), want.Page()
+	if gotPage.SiteName != wantPage.SiteName || gotPage.CanonicalURL != wantPage.CanonicalURL || gotPage.ImageURL != wantPage.ImageURL ||
+		!sameSecond(gotPage.PublishedAt, wantPage.PublishedAt) || !sameSecond(gotPage.FetchedAt, wantPage.FetchedAt) {
+		t.Errorf("Page = %+v, want %+v", gotPage, wantPage)
+	}
+	if !slices.EqualFunc(got.Tags(), want.Tags(), domain.Tag.Equals) {
+		t.Errorf("Tags = %v, want %v (order preserved)", got.Tags(), want.Tags())
+	}
+	// 日時は秒精度で保存すればよい
+	if !sameSecond(got.SyncedAt(), want.SyncedAt()) {
+		t.Errorf("SyncedAt = %v, want %v", got.SyncedAt(), want.SyncedAt())
+	}
+	if !sameSecond(got.CreatedAt(), want.CreatedAt()) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt(), want.CreatedAt())
+	}
+	if !sameSecond(got.UpdatedAt(), want.UpdatedAt()) {
+		t.Errorf("UpdatedAt = %v, want %v", got.UpdatedAt(), want.UpdatedAt())
+	}
+	if !sameSecond(got.DeletedAt(), want.DeletedAt()) {
+		t.Errorf("DeletedAt = %v, want %v", got.DeletedAt(), want.DeletedAt())
+	}
+	if got.Version() != want.Version() {
+		t.Errorf("Version = %d, want %d", got.Version(), want.Version())
+	}
+}
+
+func sameSecond(a, b time.Time) bool {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+func mustLeaf(t *testing.T, id, title, url, platform string, tags []string, read bool, syncedAt time.Time) *domain.Leaf {
+	t.Helper()
+	// 同期したLeaf（qiita）以外は同期日時を持たず、登録日時で並ぶ
+	createdAt := syncedAt
+	if platform != domain.PlatformQiita {
+		syncedAt = time.Time{}
+	}
+	leaf, err := domain.ReconstructLeaf(id, title, "", "", url, platform, tags, domain.ReadingStateFromRead(read), domain.PageInfo{}, createdAt, createdAt, syncedAt, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("ReconstructLeaf: %v", err)
+	}
+	return leaf
+}
+
+func mustTags(t *testing.T, values ...string) []domain.Tag {
+	t.Helper()
+	tags := make([]domain.Tag, len(values))
+	for i, v := range values {
+		tag, err := domain.NewTag(v)
+		if err != nil {
+			t.Fatalf("NewTag: %v", err)
+		}
+		tags[i] = tag
+	}
+	return tags
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 空のタグの別名のリポジトリを返す（サブテストごとに呼ぶ）
+type AliasFactory func(t *testing.T) domain.TagAliasRepository
+
+// newRepoで作ったリポジトリがTagAliasRepositoryの振る舞いを満たすか検証する
+func TestTagAliasRepository(t *testing.T, newRepo AliasFactory) {
+	t.Run("PutListDelete", func(t *testing.T) { testTagAliases(t, newRepo(t)) })
+}
+
+// 別名は別名の順に返り、同じ別名の登録は置き換える
+func testTagAliases(t *testing.T, repo domain.TagAliasRepository) {
+	ctx := userContext("alice")
+	for _, pair := range [][2]string{{"golang", "Go"}, {"aws-lambda", "Lambda"}, {"golang", "go"}} {
+		alias, err := domain.NewTagAlias(pair[0], pair[1])
+		if err != nil {
+			t.Fatalf("NewTagAlias: %v", err)
+		}
+		mustDo(t, repo.PutAlias(ctx, alias))
+	}
+	assertAliases(t, repo, ctx, []string{"aws-lambda→Lambda", "golang→go"})
+	assertAliases(t, repo, userContext("bob"), nil)
+
+	mustDo(t, repo.DeleteAlias(ctx, "aws-lambda"))
+	assertAliases(t, repo, ctx, []string{"golang→go"})
+	if err := repo.DeleteAlias(ctx, "aws-lambda"); !errors.Is(err, domain.ErrTagAliasNotFound) {
+		t.Errorf("DeleteAlias(missing) error = %v, want ErrTagAliasNotFound", err)
+	}
+	// 他の利用者の別名は削除できない
+	if err := repo.DeleteAlias(userContext("bob"), "golang"); !errors.Is(err, domain.ErrTagAliasNotFound) {
+		t.Errorf("DeleteAlias(bob) error = %v, want ErrTagAliasNotFound", err)
+	}
+}
+
+func assertAliases(t *testing.T, repo domain.TagAliasRepository, ctx context.Context, want []string) {
+	t.Helper()
+	aliases, err := repo.ListAliases(ctx)
+	if err != nil {
+		t.Fatalf("ListAliases: %v", err)
+	}
+	var got []string
+	for _, a := range aliases {
+		got = append(got, a.Alias().String()+"→"+a.Tag().String())
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ListAliases = %v, want %v", got, want)
+	}
+}
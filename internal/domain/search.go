@@ -0,0 +1,28 @@
+package domain
+
+import "context"
+
+var ErrSearchQueryRequired = invalid("q", CodeRequired, "検索語を指定してください")
+
+// 全文検索で一致したLeaf（Scoreが大きいほど検索語に合う）
+type SearchHit struct {
+	LeafID string
+	Score  float64
+}
+
+// Leafのタイトル・説明・メモ・URL・タグの全文検索の索引
+// 実装はコンテキストの利用者の索引だけを扱い、ゴミ箱のLeafは索引に含めない
+// 索引は保存済みのLeafからいつでも作り直せるものとし、作成前の利用者への追加・削除は無視してよい
+// 索引を通さない書き込み（他のプロセスなど）は反映されなくてよい。Builtがfalseを返せば作り直される
+type SearchIndex interface {
+	// 利用者の索引を作成済みか
+	Built(ctx context.Context) bool
+	// 利用者の索引をloadが返すLeafで作り直す
+	// 作り直している間の追加・削除は、作り直した後に反映する
+	Rebuild(ctx context.Context, load func() ([]*Leaf, error)) error
+	// Leafを索引に追加する（追加済みなら置き換え、ゴミ箱のLeafなら索引から外す）
+	Index(ctx context.Context, leaf *Leaf)
+	Remove(ctx context.Context, id string)
+	// 検索語のすべての語を含むLeafをスコアの高い順にlimit件まで返す
+	Search(ctx context.Context, query string, limit int) ([]SearchHit, error)
+}
@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+)
+
+var ErrTagAliasNotFound = newError(ErrNotFound, "tag_alias_not_found", "タグの別名が見つかりません。")
+
+// タグの別名（例: golang → Go）
+// 利用者ごとに登録し、Leafに付けるタグを正式なタグにそろえる
+type TagAlias struct {
+	alias Tag
+	tag   Tag
+}
+
+func NewTagAlias(alias, tag string) (TagAlias, error) {
+	var v validator
+	aliasTag, err := NewTag(alias)
+	v.add("alias", err)
+	target, err := NewTag(tag)
+	v.add("tag", err)
+	if err := v.err(); err != nil {
+		return TagAlias{}, err
+	}
+	if aliasTag.Equals(target) {
+		return TagAlias{}, invalid("tag", CodeDuplicate, "別名と同じタグは指定できません")
+	}
+	return TagAlias{alias: aliasTag, tag: target}, nil
+}
+
+func (a TagAlias) Alias() Tag { return a.alias }
+func (a TagAlias) Tag() Tag   { return a.tag }
+
+// 別名の保存先
+type TagAliasRepository interface {
+	// 利用者の別名を別名の順に返す
+	ListAliases(ctx context.Context) ([]TagAlias, error)
+	// 別名を登録する（同じ別名があれば置き換える）
+	PutAlias(ctx context.Context, alias TagAlias) error
+	// 別名を削除する（なければErrTagAliasNotFound）
+	DeleteAlias(ctx context.Context, alias string) error
+}
+
+// タグの正規化の設定
+type TagPolicy struct {
+	// 大文字・小文字を区別しない（Unicodeのケースフォールディングで小文字にそろえる）
+	FoldCase bool
+	// タグの最大文字数（0ならDefaultMaxTagLength）
+	MaxLength int
+	// タグに使える文字（nilなら表示できる文字すべて。階層の区切り/は常に使える）
+	AllowedChars *regexp.Regexp
+}
+
+// TagPolicyで最大文字数を指定しないときの上限
+const DefaultMaxTagLength = 64
+
+// 設定値の文字列からTagPolicyを作る（空の値は既定値）
+// foldCaseはtrue/false、maxLengthは最大文字数
+// allowedCharsは使える文字を表す正規表現の文字クラスの中身（例: \p{L}\p{N} _.+#-）
+func ParseTagPolicy(foldCase, maxLength, allowedChars string) (TagPolicy, error) {
+	var policy TagPolicy
+	if foldCase != "" {
+		v, err := strconv.ParseBool(foldCase)
+		if err != nil {
+			return TagPolicy{}, fmt.Errorf("大文字・小文字の設定が正しくありません: %s", foldCase)
+		}
+		policy.FoldCase = v
+	}
+	if maxLength != "" {
+		v, err := strconv.Atoi(maxLength)
+		if err != nil || v <= 0 || v > TagLengthLimit {
+			return TagPolicy{}, fmt.Errorf("最大文字数は1〜%dで指定してください: %s", TagLengthLimit, maxLength)
+		}
+		policy.MaxLength = v
+	}
+	if allowedChars != "" {
+		re, err := regexp.Compile(`^(?:[` + allowedChars + `]|` + TagSeparator + `)+$`)
+		if err != nil {
+			return TagPolicy{}, fmt.Errorf("使える文字の指定が正しくありません: %w", err)
+		}
+		policy.AllowedChars = re
+	}
+	return policy, nil
+}
+
+func (p TagPolicy) maxLength() int {
+	if p.MaxLength == 0 {
+		return DefaultMaxTagLength
+	}
+	return p.MaxLength
+}
+
+// 設定した長さと文字の制限を満たすか検証する
+func (p TagPolicy) check(t Tag) error {
+	if limit := p.maxLength(); utf8.RuneCountInString(t.value) > limit {
+		return invalid("tags", CodeTooLong, "Tagは"+strconv.Itoa(limit)+"文字以下である必要があります")
+	}
+	if p.AllowedChars != nil && !p.AllowedChars.MatchString(t.value) {
+		return invalid("tags", CodeInvalidFormat, "Tagに使えない文字が含まれています")
+	}
+	return nil
+}
+
+// Leafに付けるタグを正規化し、別名を正式なタグに置き換える
+// 手順: NewTagの正規化（空白・NFKC）→ ケースフォールディング（設定時）→ 別名の解決（1段階のみ）→ 長さと文字の検証
+type TagNormalizer struct {
+	policy  TagPolicy
+	aliases map[string]string // 正規化した別名 → 正式なタグ
+}
+
+func NewTagNormalizer(policy TagPolicy, aliases []TagAlias) *TagNormalizer {
+	n := &TagNormalizer{policy: policy, aliases: make(map[string]string, len(aliases))}
+	for _, a := range aliases {
+		n.aliases[n.fold(a.alias.value)] = n.fold(a.tag.value)
+	}
+	return n
+}
+
+func (n *TagNormalizer) fold(value string) string {
+	if !n.policy.FoldCase {
+		return value
+	}
+	return cases.Fold().String(value)
+}
+
+// 正規化したタグ（不正な値は項目名をfieldにした検証エラー）
+func (n *TagNormalizer) Normalize(field, value string) (string, error) {
+	var v validator
+	t, err := n.normalize(value)
+	v.add(field, err)
+	if err := v.err(); err != nil {
+		return "", err
+	}
+	return t.value, nil
+}
+
+func (n *TagNormalizer) normalize(value string) (Tag, error) {
+	t, err := NewTag(value)
+	if err != nil {
+		return Tag{}, err
+	}
+	t.value = n.fold(t.value)
+	if tag, ok := n.aliases[t.value]; ok {
+		t.value = tag
+	}
+	return t, n.policy.check(t)
+}
+
+// 複数のタグを正規化する
+// 正規化や別名の解決で同じになったタグは最初の1つにまとめる
+// 不正な値があれば、入力での位置（tags[i]）を項目名にした検証エラーを返す
+func (n *TagNormalizer) NormalizeAll(values []string) ([]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+	var v validator
+	normalized := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for i, value := range values {
+		t, err := n.normalize(value)
+		if err != nil {
+			v.add(tagField(i), err)
+			continue
+		}
+		if _, dup := seen[t.value]; dup {
+			continue
+		}
+		seen[t.value] = struct{}{}
+		normalized = append(normalized, t.value)
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTagPolicy(t *testing.T) {
+	policy, err := ParseTagPolicy("", "", "")
+	if err != nil {
+		t.Fatalf("ParseTagPolicy(defaults): %v", err)
+	}
+	if policy.FoldCase || policy.maxLength() != DefaultMaxTagLength || policy.AllowedChars != nil {
+		t.Errorf("defaults = %+v", policy)
+	}
+
+	policy, err = ParseTagPolicy("true", "10", `\p{L}\p{N}`)
+	if err != nil {
+		t.Fatalf("ParseTagPolicy: %v", err)
+	}
+	if !policy.FoldCase || policy.maxLength() != 10 || policy.AllowedChars == nil {
+		t.Errorf("policy = %+v", policy)
+	}
+
+	for _, tt := range []struct{ foldCase, maxLength, allowedChars string }{
+		{"yes", "", ""},
+		{"", "0", ""},
+		{"", "-1", ""},
+		{"", "257", ""},
+		{"", "ten", ""},
+		{"", "", `\p{Unknown}`},
+	} {
+		if _, err := ParseTagPolicy(tt.foldCase, tt.maxLength, tt.allowedChars); err == nil {
+			t.Errorf("ParseTagPolicy(%q, %q, %q) = nil error", tt.foldCase, tt.maxLength, tt.allowedChars)
+		}
+	}
+}
+
+func TestTagNormalizerPolicy(t *testing.T) {
+	policy, err := ParseTagPolicy("true", "8", `\p{L}\p{N}_-`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias, err := NewTagAlias("k8s", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewTagNormalizer(policy, []TagAlias{alias})
+
+	got, err := n.NormalizeAll([]string{"Go", "aws/EC2", "ＧＯ", "go_lang"})
+	if err != nil {
+		t.Fatalf("NormalizeAll: %v", err)
+	}
+	if want := []string{"go", "aws/ec2", "go_lang"}; !slices.Equal(got, want) {
+		t.Errorf("NormalizeAll = %v, want %v", got, want)
+	}
+
+	// 長さと文字の制限は別名を解決した後のタグで検証し、入力での位置で報告する
+	_, err = n.NormalizeAll([]string{"go", "c++", "k8s", strings.Repeat("a", 9), ""})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("NormalizeAll error = %v, want ValidationErrors", err)
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field+":"+e.Code)
+	}
+	if want := []string{"tags[1]:invalid_format", "tags[2]:too_long", "tags[3]:too_long", "tags[4]:required"}; !slices.Equal(fields, want) {
+		t.Errorf("errors = %v, want %v", fields, want)
+	}
+
+	if _, err := n.Normalize("to", "c#"); !hasField(err, "to") {
+		t.Errorf("Normalize(c#) error = %v, want an error on to", err)
+	}
+	if got, err := n.Normalize("to", " Rust "); err != nil || got != "rust" {
+		t.Errorf("Normalize(Rust) = %q, %v", got, err)
+	}
+}
+
+func TestTagNormalizerDefaultPolicy(t *testing.T) {
+	n := NewTagNormalizer(TagPolicy{}, nil)
+	if _, err := n.NormalizeAll([]string{strings.Repeat("a", DefaultMaxTagLength)}); err != nil {
+		t.Errorf("NormalizeAll(%d chars): %v", DefaultMaxTagLength, err)
+	}
+	if _, err := n.NormalizeAll([]string{strings.Repeat("a", DefaultMaxTagLength+1)}); !hasField(err, "tags[0]") {
+		t.Errorf("NormalizeAll(%d chars) error = %v, want an error on tags[0]", DefaultMaxTagLength+1, err)
+	}
+	// 大文字・小文字は区別し、表示できる文字はすべて使える
+	if got, err := n.NormalizeAll([]string{"C++", "c#", "日本語"}); err != nil || !slices.Equal(got, []string{"C++", "c#", "日本語"}) {
+		t.Errorf("NormalizeAll = %v, %v", got, err)
+	}
+}
+
+func TestTagReplacementValidatePolicy(t *testing.T) {
+	policy, err := ParseTagPolicy("", "12", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewTagMove("lambda", "aws/compute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// aws/compute/lambda は12文字を超える
+	if err := r.Validate([]string{"lambda"}, policy); !hasField(err, "parent") {
+		t.Errorf("Validate error = %v, want an error on parent", err)
+	}
+	if err := r.Validate([]string{"lambda"}, TagPolicy{}); err != nil {
+		t.Errorf("Validate(default policy) = %v", err)
+	}
+}
+
+func hasField(err error, field string) bool {
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	return slices.ContainsFunc(errs, func(e *ValidationError) bool { return e.Field == field })
+}
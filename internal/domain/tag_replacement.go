@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// タグの一括付け替え（改名・統合・削除・階層の移動）
+// fromのタグを外し、toがあれば外したタグの位置にtoを付ける
+// fromは保存済みのタグと完全に一致するものだけが対象（正規化前の古いタグも指定できる）
+// toは新しく付けるタグなので正規化する
+// 階層の移動ではfromの1つ目のタグとその子孫を、toとその子孫に付け替える
+type TagReplacement struct {
+	from    []string
+	to      string // 空なら外すだけ
+	subtree bool   // 階層の移動
+}
+
+// 外すタグ（一覧の絞り込み条件として使える。階層の移動では子孫のパターンを含む）
+func (r TagReplacement) From() []string {
+	if r.subtree {
+		return []string{r.from[0], r.from[0] + TagSeparator + tagWildcard}
+	}
+	return slices.Clone(r.from)
+}
+
+// 付けるタグ（削除なら空）
+func (r TagReplacement) To() string { return r.to }
+
+// タグの改名
+func NewTagRename(from, to string) (TagReplacement, error) {
+	var v validator
+	fromTag, err := restoreTag(from)
+	v.add("name", err)
+	toTag, err := NewTag(to)
+	v.add("to", err)
+	if err := v.err(); err != nil {
+		return TagReplacement{}, err
+	}
+	if fromTag.Equals(toTag) {
+		return TagReplacement{}, invalid("to", CodeDuplicate, "変更前と同じタグです")
+	}
+	return TagReplacement{from: []string{fromTag.value}, to: toTag.value}, nil
+}
+
+// 複数のタグを1つに統合する（targetがsourcesに含まれていてもよい）
+func NewTagMerge(sources []string, target string) (TagReplacement, error) {
+	var v validator
+	targetTag, err := NewTag(target)
+	v.add("target", err)
+	var from []string
+	for i, source := range sources {
+		t, err := restoreTag(source)
+		if err != nil {
+			v.add("sources["+strconv.Itoa(i)+"]", err)
+			continue
+		}
+		if !t.Equals(targetTag) && !slices.Contains(from, t.value) {
+			from = append(from, t.value)
+		}
+	}
+	if err := v.err(); err != nil {
+		return TagReplacement{}, err
+	}
+	if len(from) == 0 {
+		return TagReplacement{}, invalid("sources", CodeRequired, "統合するタグを指定してください")
+	}
+	return TagReplacement{from: from, to: targetTag.value}, nil
+}
+
+// タグの削除
+func NewTagRemoval(tag string) (TagReplacement, error) {
+	var v validator
+	t, err := restoreTag(tag)
+	v.add("name", err)
+	if err := v.err(); err != nil {
+		return TagReplacement{}, err
+	}
+	return TagReplacement{from: []string{t.value}}, nil
+}
+
+// タグの階層を移動する（tagとその子孫をparentの下へ。parentが空なら最上位へ）
+// 例: aws/lambdaをcloudの下へ移動すると、aws/lambda/edgeはcloud/lambda/edgeになる
+func NewTagMove(tag, parent string) (TagReplacement, error) {
+	var v validator
+	t, err := restoreTag(tag)
+	v.add("name", err)
+	var parentTag Tag
+	if parent != "" {
+		parentTag, err = NewTag(parent)
+		v.add("parent", err)
+	}
+	if err := v.err(); err != nil {
+		return TagReplacement{}, err
+	}
+	if parentTag.Equals(t) || parentTag.IsDescendantOf(t) {
+		return TagReplacement{}, invalid("parent", CodeInvalidFormat, "移動するタグ自身やその子孫の下へは移動できません")
+	}
+	segments := t.Segments()
+	to := segments[len(segments)-1]
+	if parent != "" {
+		to = parentTag.value + TagSeparator + to
+	}
+	toTag, err := NewTag(to)
+	v.add("parent", err)
+	if err := v.err(); err != nil {
+		return TagReplacement{}, err
+	}
+	if toTag.Equals(t) {
+		return TagReplacement{}, invalid("parent", CodeDuplicate, "移動前と同じ階層です")
+	}
+	return TagReplacement{from: []string{t.value}, to: toTag.value, subtree: true}, nil
+}
+
+// 付け替え後のタグがすべてNewTagとpolicyの規則を満たすか検証する（tagsは対象のLeafに付いているタグ）
+// 階層の移動では子孫のタグが長さの上限を超えることがあるので、書き込む前に確認する
+func (r TagReplacement) Validate(tags []string, policy TagPolicy) error {
+	field := "to"
+	if r.subtree {
+		field = "parent"
+	}
+	var v validator
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		to, ok, err := r.replace(Tag{value: tag})
+		if err == nil && ok && to.value != "" {
+			err = policy.check(to)
+		}
+		if err != nil {
+			code := CodeInvalidFormat
+			var ve *ValidationError
+			if errors.As(err, &ve) {
+				code = ve.Code
+			}
+			v.add(field, invalid(field, code, tag+"の付け替え後のタグが正しくありません（"+err.Error()+"）"))
+		}
+	}
+	return v.err()
+}
+
+// 付け替え後のタグ（対象でなければfalse、外すだけならゼロ値）
+// 階層の移動で子孫のタグから作ったタグはNewTagで検証する
+func (r TagReplacement) replace(t Tag) (Tag, bool, error) {
+	if r.subtree {
+		root := Tag{value: r.from[0]}
+		if !t.Equals(root) && !t.IsDescendantOf(root) {
+			return Tag{}, false, nil
+		}
+		to, err := NewTag(r.to + strings.TrimPrefix(t.value, root.value))
+		return to, true, err
+	}
+	if !slices.Contains(r.from, t.value) {
+		return Tag{}, false, nil
+	}
+	return Tag{value: r.to}, true, nil
+}
+
+// タグを付け替える（対象のタグが付いていなければ何もせずfalse）
+// 付け替え後のタグはUpdateTagsと同じ規則で検証する
+func (l *Leaf) ReplaceTags(r TagReplacement) (bool, error) {
+	tags := make([]Tag, 0, len(l.tags))
+	// 付け替え先のタグが既に付いていれば外すだけにする
+	seen := make(map[string]bool, len(l.tags))
+	for _, t := range l.tags {
+		if _, ok, _ := r.replace(t); !ok {
+			seen[t.value] = true
+		}
+	}
+	replaced := false
+	for _, t := range l.tags {
+		to, ok, err := r.replace(t)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			tags = append(tags, t)
+			continue
+		}
+		// 外したタグの位置に付け替え先のタグを付ける
+		if to.value != "" && !seen[to.value] {
+			tags = append(tags, to)
+			seen[to.value] = true
+		}
+		replaced = true
+	}
+	if !replaced {
+		return false, nil
+	}
+	if err := l.UpdateTags(tags); err != nil {
+		return false, err
+	}
+	return true, nil
+}
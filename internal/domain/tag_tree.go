@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// タグの階層の区切り
+const TagSeparator = "/"
+
+// 子孫のタグすべてに一致する絞り込みのパターンの末尾（例: aws/*）
+const tagWildcard = "*"
+
+// 階層ごとの名前（例: aws/lambda → [aws lambda]）
+func (t Tag) Segments() []string {
+	return strings.Split(t.value, TagSeparator)
+}
+
+// 親のタグ（最上位ならfalse）
+func (t Tag) Parent() (Tag, bool) {
+	i := strings.LastIndex(t.value, TagSeparator)
+	if i < 0 {
+		return Tag{}, false
+	}
+	return Tag{value: t.value[:i]}, true
+}
+
+// 祖先のタグ（上位から順、自身は含まない）
+func (t Tag) Ancestors() []Tag {
+	var ancestors []Tag
+	for i, r := range t.value {
+		if string(r) == TagSeparator {
+			ancestors = append(ancestors, Tag{value: t.value[:i]})
+		}
+	}
+	return ancestors
+}
+
+// ancestorの子孫か（自身は含まない）
+func (t Tag) IsDescendantOf(ancestor Tag) bool {
+	return strings.HasPrefix(t.value, ancestor.value+TagSeparator)
+}
+
+// 絞り込みのタグの条件が子孫のパターン（aws/*）なら、その親のタグを返す
+func TagPatternParent(pattern string) (string, bool) {
+	parent, ok := strings.CutSuffix(pattern, TagSeparator+tagWildcard)
+	return parent, ok && parent != ""
+}
+
+// 絞り込みのタグの条件に一致するか
+// 条件はタグと完全に一致するか、aws/*のように子孫のタグすべてに一致するパターン
+func MatchTagPattern(pattern string, t Tag) bool {
+	if parent, ok := TagPatternParent(pattern); ok {
+		return t.IsDescendantOf(Tag{value: parent})
+	}
+	return t.value == pattern
+}
+
+// タグの階層の1ノード
+type TagNode struct {
+	Path   string // 最上位からのタグ（例: aws/lambda）
+	Name   string // 最下位の階層の名前（例: lambda）
+	Leaves int    // このタグが付いたLeafの数（子孫のタグとしてだけ使われていれば0）
+	Unread int    // うち未読のLeafの数
+	// 自身と子孫のタグの件数の合計（複数の子孫のタグが付いたLeafは重複して数える）
+	TotalLeaves int
+	TotalUnread int
+	Children    []*TagNode // 名前順
+}
+
+// タグごとの件数から階層の木を作る（最上位のノードを名前順に返す）
+// 子孫のタグにだけ現れる祖先（aws/lambdaだけが使われているときのaws）も0件のノードとして含む
+func BuildTagTree(counts []TagCount) []*TagNode {
+	nodes := make(map[string]*TagNode)
+	var roots []*TagNode
+	var node func(path string) *TagNode
+	node = func(path string) *TagNode {
+		if n, ok := nodes[path]; ok {
+			return n
+		}
+		n := &TagNode{Path: path, Name: path}
+		nodes[path] = n
+		if i := strings.LastIndex(path, TagSeparator); i >= 0 {
+			n.Name = path[i+len(TagSeparator):]
+			parent := node(path[:i])
+			parent.Children = append(parent.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+		return n
+	}
+	for _, c := range counts {
+		n := node(c.Tag)
+		n.Leaves, n.Unread = c.Leaves, c.Unread
+	}
+	for _, root := range roots {
+		sumTagNode(root)
+	}
+	sortTagNodes(roots)
+	return roots
+}
+
+func sumTagNode(n *TagNode) {
+	n.TotalLeaves, n.TotalUnread = n.Leaves, n.Unread
+	for _, child := range n.Children {
+		sumTagNode(child)
+		n.TotalLeaves += child.TotalLeaves
+		n.TotalUnread += child.TotalUnread
+	}
+}
+
+func sortTagNodes(nodes []*TagNode) {
+	slices.SortFunc(nodes, func(a, b *TagNode) int { return cmp.Compare(a.Name, b.Name) })
+	for _, n := range nodes {
+		sortTagNodes(n.Children)
+	}
+}
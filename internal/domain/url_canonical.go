@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// 記事の内容に関係しない計測用のクエリパラメータ
+var trackingParams = []string{
+	"fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "twclid", "igshid",
+	"mc_cid", "mc_eid", "_ga", "_gl", "_hsenc", "_hsmi", "mkt_tok", "ref_src", "ref_url", "spm",
+}
+
+// 計測用のクエリパラメータか（utm_*と既知のパラメータ）
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || slices.Contains(trackingParams, key)
+}
+
+// サイトごとの正規化の規則
+// ホスト名（小文字・www.なし）で選び、スキームとホスト名をそろえた後のURLを書き換える
+var siteCanonicalizers = map[string]func(u *url.URL){
+	"qiita.com":     canonicalArticle("qiita.com"),
+	"zenn.dev":      canonicalArticle("zenn.dev"),
+	"note.com":      canonicalArticle("note.com"),
+	"note.mu":       canonicalArticle("note.com"), // 旧ドメイン
+	"github.com":    canonicalGitHub,
+	"youtube.com":   canonicalYouTube,
+	"m.youtube.com": canonicalYouTube,
+	"youtu.be":      canonicalYouTube,
+}
+
+// 同じページを指すURLを1つの表記にそろえる（同一性と重複の判定に使う）
+//   - スキームとホスト名を小文字にし、既定のポートを除く
+//   - 計測用のクエリパラメータ（utm_*など）を除き、残りはキーの順に並べる
+//   - フラグメントを除く（#!で始まるハッシュバンは残す）
+//   - 末尾の/を除く（パスが空なら/）
+//   - Qiita・Zenn・note・GitHub・YouTubeはサイトごとの規則でそろえる
+//
+// 解析できないURLはそのまま返す
+func CanonicalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	}
+	if !strings.HasPrefix(u.Fragment, "!") {
+		u.Fragment, u.RawFragment = "", ""
+	}
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	if canonicalize, ok := siteCanonicalizers[strings.TrimPrefix(host, "www.")]; ok && port == "" {
+		canonicalize(u)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	if u.Path == "" {
+		u.Path, u.RawPath = "/", ""
+	}
+	return u.String()
+}
+
+// 記事のURLはhttpsでwww.なしのホスト名にそろえ、クエリを除く（記事の特定にクエリを使わないサイト）
+func canonicalArticle(host string) func(u *url.URL) {
+	return func(u *url.URL) {
+		u.Scheme, u.Host = "https", host
+		u.RawQuery = ""
+	}
+}
+
+// GitHubは所有者とリポジトリ名の大文字・小文字を区別せず、リポジトリのURLの.gitを除く
+func canonicalGitHub(u *url.URL) {
+	u.Scheme, u.Host = "https", "github.com"
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i < len(segments) && i < 2; i++ {
+		segments[i] = strings.ToLower(segments[i])
+	}
+	if len(segments) == 2 {
+		segments[1] = strings.TrimSuffix(segments[1], ".git")
+	}
+	u.Path, u.RawPath = "/"+strings.Join(segments, "/"), ""
+}
+
+// YouTubeの動画は短縮URL・Shorts・埋め込みも含めて https://www.youtube.com/watch?v=<ID> にそろえる
+// 再生位置（t）やプレイリスト（list）は同じ動画として扱う
+func canonicalYouTube(u *url.URL) {
+	var id string
+	path := strings.Trim(u.Path, "/")
+	switch {
+	case u.Host == "youtu.be":
+		id = path
+	case path == "watch":
+		id = u.Query().Get("v")
+	case strings.HasPrefix(path, "shorts/"), strings.HasPrefix(path, "embed/"), strings.HasPrefix(path, "live/"):
+		_, id, _ = strings.Cut(path, "/")
+	}
+	u.Scheme, u.Host = "https", "www.youtube.com"
+	if id == "" || strings.Contains(id, "/") {
+		return
+	}
+	u.Path, u.RawPath = "/watch", ""
+	u.RawQuery = url.Values{"v": {id}}.Encode()
+}
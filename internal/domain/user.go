@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrUnauthenticated = errors.New("ユーザーを特定できません。")
+
+// UserID Value Object
+// Leafの所有者。リポジトリのパーティションキーに使うため区切り文字#は禁止
+
+type UserID struct {
+	value string
+}
+
+func NewUserID(value string) (UserID, error) {
+	if value == "" {
+		return UserID{}, invalid("user_id", CodeRequired, "UserIDは空にできません")
+	}
+	if len(value) > 128 {
+		return UserID{}, invalid("user_id", CodeTooLong, "UserIDは128文字以下である必要があります")
+	}
+	if strings.ContainsAny(value, "#/ ") {
+		return UserID{}, invalid("user_id", CodeInvalidFormat, "UserIDに#、/、空白は使えません")
+	}
+	return UserID{value: value}, nil
+}
+
+func (id UserID) String() string {
+	return id.value
+}
+
+func (id UserID) Equals(other UserID) bool {
+	return id.value == other.value
+}
+
+type userIDKey struct{}
+
+// リクエストの利用者をコンテキストに格納する
+func WithUserID(ctx context.Context, id UserID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// コンテキストから利用者を取り出す
+// リポジトリは必ずこの利用者のデータだけを扱う
+func UserIDFromContext(ctx context.Context) (UserID, error) {
+	id, ok := ctx.Value(userIDKey{}).(UserID)
+	if !ok || id.value == "" {
+		return UserID{}, ErrUnauthenticated
+	}
+	return id, nil
+}
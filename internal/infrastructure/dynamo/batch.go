@@ -0,0 +1,101 @@
+package dynamo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// BatchGetItem・BatchWriteItemで一度に扱える項目の最大数
+const (
+	batchGetLimit   = 100
+	batchWriteLimit = 25
+)
+
+// 未処理キー・項目を再試行する最大回数
+const maxBatchRetries = 8
+
+// キーに一致するアイテムをまとめて読む（存在しないキーは結果に含まれない、順序は不定）
+func (r *LeafDynamoRepository) batchGet(ctx context.Context, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	// BatchGetItemは重複したキーを受け付けない
+	keys = slices.CompactFunc(slices.SortedFunc(slices.Values(keys), compareKeys), func(a, b map[string]types.AttributeValue) bool {
+		return compareKeys(a, b) == 0
+	})
+	var items []map[string]types.AttributeValue
+	for chunk := range slices.Chunk(keys, batchGetLimit) {
+		request := map[string]types.KeysAndAttributes{
+			r.TableName: {Keys: chunk, ConsistentRead: aws.Bool(true)},
+		}
+		for attempt := 0; len(request) > 0; attempt++ {
+			if err := waitRetry(ctx, attempt); err != nil {
+				return nil, err
+			}
+			out, err := r.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, out.Responses[r.TableName]...)
+			request = out.UnprocessedKeys
+		}
+	}
+	return items, nil
+}
+
+// 書き込みをまとめて実行する（条件は付けられず、既存のアイテムは上書きされる）
+func (r *LeafDynamoRepository) batchWrite(ctx context.Context, writes []types.WriteRequest) error {
+	for chunk := range slices.Chunk(writes, batchWriteLimit) {
+		request := map[string][]types.WriteRequest{r.TableName: chunk}
+		for attempt := 0; len(request) > 0; attempt++ {
+			if err := waitRetry(ctx, attempt); err != nil {
+				return err
+			}
+			out, err := r.Client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: request})
+			if err != nil {
+				return err
+			}
+			request = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// 再試行の前に待つ（初回は待たない、指数バックオフ、上限回数を超えたらエラー）
+func waitRetry(ctx context.Context, attempt int) error {
+	if attempt == 0 {
+		return nil
+	}
+	if attempt > maxBatchRetries {
+		return fmt.Errorf("%d回再試行しても処理できない項目が残りました", maxBatchRetries)
+	}
+	delay := min(50*time.Millisecond<<(attempt-1), 5*time.Second)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// アイテムのキー（pk, sk）。キー属性はどちらも文字列型
+func keyOf(item map[string]types.AttributeValue) [2]string {
+	return [2]string{stringValue(item["pk"]), stringValue(item["sk"])}
+}
+
+func compareKeys(a, b map[string]types.AttributeValue) int {
+	ka, kb := keyOf(a), keyOf(b)
+	return cmp.Or(strings.Compare(ka[0], kb[0]), strings.Compare(ka[1], kb[1]))
+}
+
+func stringValue(v types.AttributeValue) string {
+	if s, ok := v.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
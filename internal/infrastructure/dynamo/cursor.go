@@ -0,0 +1,66 @@
+package dynamo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// クライアントに渡すカーソルの中身
+// 同じインデックスを使う別の並び順（昇順・降順）で使われないよう、並び替え条件も持つ
+type cursor struct {
+	SortBy string            `json:"s"`
+	Desc   bool              `json:"d"`
+	Key    map[string]string `json:"k"`
+}
+
+// LastEvaluatedKeyをクライアントに渡す不透明なカーソル文字列に変換する
+// キー属性はすべて文字列型である前提
+func encodeCursor(key map[string]types.AttributeValue, opts domain.ListOptions) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	c := cursor{SortBy: opts.SortBy, Desc: opts.SortDesc, Key: make(map[string]string, len(key))}
+	for name, av := range key {
+		s, ok := av.(*types.AttributeValueMemberS)
+		if !ok {
+			return "", domain.ErrInvalidCursor
+		}
+		c.Key[name] = s.Value
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// カーソル文字列をExclusiveStartKeyに戻す
+// 別パーティションのキーや、別の並び替え条件で発行されたカーソルは受け付けない
+func decodeCursor(s string, pk string, opts domain.ListOptions, keyAttrs []string) (map[string]types.AttributeValue, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+	if c.SortBy != opts.SortBy || c.Desc != opts.SortDesc {
+		return nil, domain.ErrInvalidCursor
+	}
+	if c.Key["pk"] != pk || len(c.Key) != len(keyAttrs) {
+		return nil, domain.ErrInvalidCursor
+	}
+	key := make(map[string]types.AttributeValue, len(c.Key))
+	for _, name := range keyAttrs {
+		v, ok := c.Key[name]
+		if !ok || v == "" {
+			return nil, domain.ErrInvalidCursor
+		}
+		key[name] = &types.AttributeValueMemberS{Value: v}
+	}
+	return key, nil
+}
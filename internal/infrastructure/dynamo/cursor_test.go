@@ -0,0 +1,54 @@
+package dynamo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+func TestCursor(t *testing.T) {
+	const pk = "USER#alice"
+	index, err := sortIndexFor(domain.SortByTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := map[string]types.AttributeValue{
+		"pk":         &types.AttributeValueMemberS{Value: pk},
+		"sk":         &types.AttributeValueMemberS{Value: "l1"},
+		"title_sort": &types.AttributeValueMemberS{Value: "apple\x00l1"},
+	}
+	opts := domain.ListOptions{SortBy: domain.SortByTitle}
+	s, err := encodeCursor(key, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decodeCursor(s, pk, opts, index.keyAttributes())
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if v := got["title_sort"].(*types.AttributeValueMemberS).Value; v != "apple\x00l1" {
+		t.Errorf("title_sort = %q", v)
+	}
+
+	// 並び替え条件やパーティションが異なるリクエストでは使えない
+	invalid := []struct {
+		name string
+		pk   string
+		opts domain.ListOptions
+	}{
+		{"降順", pk, domain.ListOptions{SortBy: domain.SortByTitle, SortDesc: true}},
+		{"別の並び替え", pk, domain.ListOptions{SortBy: domain.SortByRead}},
+		{"別の利用者", "USER#bob", opts},
+	}
+	for _, tt := range invalid {
+		if _, err := decodeCursor(s, tt.pk, tt.opts, index.keyAttributes()); !errors.Is(err, domain.ErrInvalidCursor) {
+			t.Errorf("%s: decodeCursor error = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+	if _, err := decodeCursor("not a cursor", pk, opts, index.keyAttributes()); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("decodeCursor(garbage) error = %v, want ErrInvalidCursor", err)
+	}
+}
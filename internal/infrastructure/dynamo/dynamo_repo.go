@@ -2,7 +2,8 @@ package dynamo
 
 import (
 	"context"
-	"errors"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -10,11 +11,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/storage"
 )
 
 type LeafDynamoRepository struct {
 	Client    *dynamodb.Client
 	TableName string
+	// ゴミ箱のLeafを保持する期間（0なら無期限）
+	// 期限を過ぎたLeafはTTLで削除される（削除は期限から数日遅れることがある）
+	TrashRetention time.Duration
 }
 
 func NewLeafDynamoRepository(client *dynamodb.Client, tableName string) *LeafDynamoRepository {
@@ -25,150 +30,556 @@ func NewLeafDynamoRepository(client *dynamodb.Client, tableName string) *LeafDyn
 }
 
 func (r *LeafDynamoRepository) Get(ctx context.Context, id string) (*domain.Leaf, error) {
+	pk, err := userPartition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	record, err := r.getRecord(ctx, pk, id)
+	if err != nil {
+		return nil, err
+	}
+	return RecordToLeaf(record)
+}
+
+// 保存済みのレコードを読む（なければErrLeafNotFound）
+// 読んだ直後に条件付きで書き込めるよう強い整合性で読む
+func (r *LeafDynamoRepository) getRecord(ctx context.Context, pk, id string) (*LeafRecord, error) {
 	output, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: &r.TableName,
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "USER#me"},
-			"sk": &types.AttributeValueMemberS{Value: id},
-		},
+		TableName:      &r.TableName,
+		Key:            leafKey(pk, id),
+		ConsistentRead: aws.Bool(true),
 	})
 	if err != nil {
 		return nil, err
 	}
 	if output.Item == nil {
-		return nil, errors.New("leaf not found")
+		return nil, domain.ErrLeafNotFound
 	}
 	var record LeafRecord
 	if err := attributevalue.UnmarshalMap(output.Item, &record); err != nil {
 		return nil, err
 	}
-	return RecordToLeaf(&record)
+	return &record, nil
 }
 
-func (r *LeafDynamoRepository) List(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, error) {
+func (r *LeafDynamoRepository) List(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, string, error) {
+	pk, err := userPartition(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	index, err := sortIndexFor(opts.SortBy)
+	if err != nil {
+		return nil, "", err
+	}
+	// 1つのタグを必ず含む条件なら、そのタグの索引のパーティションだけを読む
+	// 子孫のパターン（aws/*）は索引がないので、利用者のパーティションを絞り込む
+	if tag, ok := requiredTag(opts); ok {
+		pk = tagPartition(pk, tag)
+	}
 	// QueryInputの作成
 	queryInput := &dynamodb.QueryInput{
 		TableName:              &r.TableName,
 		KeyConditionExpression: aws.String("pk = :pk"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "USER#me"},
+			":pk": &types.AttributeValueMemberS{Value: pk},
 		},
+		ScanIndexForward: aws.Bool(!opts.SortDesc),
 	}
-	// Limitの適用
-	if opts.Limit > 0 {
-		queryInput.Limit = aws.Int32(int32(opts.Limit))
+	// 並び替えの適用（GSIのソートキー順に読む）
+	if index != nil {
+		queryInput.IndexName = aws.String(index.Name)
 	}
-	// フィルタリングの適用
-	queryOut, err := r.Client.Query(ctx, queryInput)
-	if err != nil {
-		return nil, err
+	// カーソルの適用
+	if opts.Cursor != "" {
+		startKey, err := decodeCursor(opts.Cursor, pk, opts, index.keyAttributes())
+		if err != nil {
+			return nil, "", err
+		}
+		queryInput.ExclusiveStartKey = startKey
 	}
-	var records []LeafRecord
-	if err := attributevalue.UnmarshalListOfMaps(queryOut.Items, &records); err != nil {
-		return nil, err
+	// フィルタリングの適用
+	if expr, names, values := buildFilter(opts); expr != "" {
+		queryInput.FilterExpression = aws.String(expr)
+		queryInput.ExpressionAttributeNames = names
+		for k, v := range values {
+			queryInput.ExpressionAttributeValues[k] = v
+		}
 	}
+	// FilterExpressionはLimit件を読んだ後に適用されるため、
+	// Limit件集まるかパーティションを読み切るまでQueryを繰り返す
 	var leaves []domain.Leaf
-	for _, r := range records {
-		leaf, err := RecordToLeaf(&r)
+	for {
+		// Limitの適用（残り件数だけ評価させ、最後に返した項目の直後から再開できるようにする）
+		if opts.Limit > 0 {
+			queryInput.Limit = aws.Int32(int32(opts.Limit - len(leaves)))
+		}
+		queryOut, err := r.Client.Query(ctx, queryInput)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
-		leaves = append(leaves, *leaf)
+		var records []LeafRecord
+		if err := attributevalue.UnmarshalListOfMaps(queryOut.Items, &records); err != nil {
+			return nil, "", err
+		}
+		for _, r := range records {
+			leaf, err := RecordToLeaf(&r)
+			if err != nil {
+				return nil, "", err
+			}
+			leaves = append(leaves, *leaf)
+		}
+		if queryOut.LastEvaluatedKey == nil {
+			return leaves, "", nil
+		}
+		if opts.Limit > 0 && len(leaves) >= opts.Limit {
+			next, err := encodeCursor(queryOut.LastEvaluatedKey, opts)
+			if err != nil {
+				return nil, "", err
+			}
+			return leaves, next, nil
+		}
+		queryInput.ExclusiveStartKey = queryOut.LastEvaluatedKey
 	}
-	return leaves, nil
 }
 
 func (r *LeafDynamoRepository) Put(ctx context.Context, leaf *domain.Leaf) (*domain.Leaf, error) {
-	item, err := attributevalue.MarshalMap(LeafToRecord(leaf))
+	pk, err := userPartition(ctx)
 	if err != nil {
 		return nil, err
 	}
-	putItem, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: &r.TableName,
-		Item:      item,
-	})
+	record := r.record(pk, leaf)
+	item, err := attributevalue.MarshalMap(record)
 	if err != nil {
 		return nil, err
 	}
-	if putItem == nil {
-		return nil, errors.New("DBに保存できませんでした")
+	urlPut, err := putURLIndex(r.TableName, record)
+	if err != nil {
+		return nil, err
+	}
+	tagWrites, err := r.tagIndexWrites(pk, nil, record)
+	if err != nil {
+		return nil, err
+	}
+	// 既存のLeafを上書きせず、URLとタグの索引と同時に作成する
+	_, err = r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:           &r.TableName,
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(sk)"),
+			}},
+			urlPut,
+		}, tagWrites...),
+	})
+	if reasons := cancellationReasons(err); reasons != nil {
+		switch {
+		case conditionFailed(reasons[1]):
+			return nil, duplicateURLError(reasons[1].Item)
+		case conditionFailed(reasons[0]), transactionConflict(reasons):
+			return nil, domain.ErrVersionConflict
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
+	leaf.IncrementVersion()
 	return leaf, nil
 }
 
+func (r *LeafDynamoRepository) PutMany(ctx context.Context, leaves []*domain.Leaf) ([]*domain.Leaf, error) {
+	pk, err := userPartition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// BatchWriteItemは条件を付けられないため、登録済みのIDとURLを先に調べて除外する
+	keys := make([]map[string]types.AttributeValue, 0, 2*len(leaves))
+	for _, leaf := range leaves {
+		keys = append(keys, leafKey(pk, leaf.ID().String()), urlIndexKey(pk, leaf.URL().String()))
+	}
+	existing, err := r.batchGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[[2]string]struct{}, len(existing))
+	for _, item := range existing {
+		taken[keyOf(item)] = struct{}{}
+	}
+
+	var stored []*domain.Leaf
+	var writes []types.WriteRequest
+	deltas := make(map[string]tagDelta)
+	for _, leaf := range leaves {
+		idKey, urlKey := keyOf(leafKey(pk, leaf.ID().String())), keyOf(urlIndexKey(pk, leaf.URL().String()))
+		if _, ok := taken[idKey]; ok {
+			continue
+		}
+		if _, ok := taken[urlKey]; ok {
+			continue
+		}
+		taken[idKey], taken[urlKey] = struct{}{}, struct{}{}
+
+		record := r.record(pk, leaf)
+		item, err := attributevalue.MarshalMap(record)
+		if err != nil {
+			return nil, err
+		}
+		urlItem, err := urlIndexItem(pk, record.URL, record.ID, record.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes,
+			types.WriteRequest{PutRequest: &types.PutRequest{Item: item}},
+			types.WriteRequest{PutRequest: &types.PutRequest{Item: urlItem}},
+		)
+		for _, tag := range record.Tags {
+			copied, err := tagCopy(record, tag)
+			if err != nil {
+				return nil, err
+			}
+			writes = append(writes, types.WriteRequest{PutRequest: &types.PutRequest{Item: copied}})
+		}
+		for tag, d := range tagDeltas(nil, record) {
+			sum := deltas[tag]
+			deltas[tag] = tagDelta{leaves: sum.leaves + d.leaves, unread: sum.unread + d.unread}
+		}
+		stored = append(stored, leaf)
+	}
+	if err := r.batchWrite(ctx, writes); err != nil {
+		return nil, err
+	}
+	if err := r.addTagCounts(ctx, pk, deltas); err != nil {
+		return nil, err
+	}
+	for _, leaf := range stored {
+		leaf.IncrementVersion()
+	}
+	return stored, nil
+}
+
 func (r *LeafDynamoRepository) Update(ctx context.Context, update *domain.Leaf) error {
-	item, err := attributevalue.MarshalMap(LeafToRecord(update))
+	pk, err := userPartition(ctx)
 	if err != nil {
 		return err
 	}
-	_, err = r.Client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: &r.TableName,
-		Item:      item,
-	})
+	id := update.ID().String()
+	stored, err := r.getRecord(ctx, pk, id)
+	if err != nil {
+		return err
+	}
+	record := r.record(pk, update)
+	item, err := attributevalue.MarshalMap(record)
 	if err != nil {
 		return err
 	}
+	// 読み込んだ時点のバージョンのままである場合だけ書き込む
+	cond, names, values := versionCondition(update.Version())
+	items := []types.TransactWriteItem{
+		{Put: &types.Put{
+			TableName:                           &r.TableName,
+			Item:                                item,
+			ConditionExpression:                 aws.String(cond),
+			ExpressionAttributeNames:            names,
+			ExpressionAttributeValues:           values,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		}},
+	}
+	switch {
+	case domain.CanonicalizeURL(stored.URL) != domain.CanonicalizeURL(record.URL):
+		// URLを変更した場合は索引を付け替える
+		urlPut, err := putURLIndex(r.TableName, record)
+		if err != nil {
+			return err
+		}
+		remove, err := r.removeURLIndex(ctx, pk, stored)
+		if err != nil {
+			return err
+		}
+		items = append(append(items, urlPut), remove...)
+	case stored.ExpiresAt != record.ExpiresAt:
+		// ゴミ箱への移動・復元に合わせて索引の有効期限も変える
+		owner, err := r.urlOwner(ctx, pk, stored.URL)
+		if err != nil {
+			return err
+		}
+		if owner == stored.ID {
+			urlPut, err := putURLIndex(r.TableName, record)
+			if err != nil {
+				return err
+			}
+			items = append(items, urlPut)
+		}
+	}
+	tagWrites, err := r.tagIndexWrites(pk, stored, record)
+	if err != nil {
+		return err
+	}
+	items = append(items, tagWrites...)
+	_, err = r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if reasons := cancellationReasons(err); reasons != nil {
+		switch {
+		case conditionFailed(reasons[0]) && reasons[0].Item == nil:
+			return domain.ErrLeafNotFound
+		case conditionFailed(reasons[0]):
+			return domain.ErrVersionConflict
+		case len(reasons) > 1 && conditionFailed(reasons[1]):
+			return duplicateURLError(reasons[1].Item)
+		case slices.ContainsFunc(reasons, conditionFailed), transactionConflict(reasons):
+			return domain.ErrVersionConflict
+		}
+	}
+	if err != nil {
+		return err
+	}
+	update.IncrementVersion()
 	return nil
 }
 
 func (r *LeafDynamoRepository) Delete(ctx context.Context, id string) error {
-	old, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: &r.TableName,
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "USER#me"},
-			"sk": &types.AttributeValueMemberS{Value: id},
-		},
-		ReturnValues: types.ReturnValueAllOld,
-	})
+	pk, err := userPartition(ctx)
 	if err != nil {
 		return err
 	}
-	if old.Attributes == nil {
-		return errors.New("leaf not found")
+	stored, err := r.getRecord(ctx, pk, id)
+	if err != nil {
+		return err
 	}
-	return nil
+	// 読み込んだ後に更新・削除されていなければ、URLとタグの索引とともに削除する
+	cond, names, values := versionCondition(stored.Version)
+	items := []types.TransactWriteItem{
+		{Delete: &types.Delete{
+			TableName:                           &r.TableName,
+			Key:                                 leafKey(pk, id),
+			ConditionExpression:                 aws.String(cond),
+			ExpressionAttributeNames:            names,
+			ExpressionAttributeValues:           values,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		}},
+	}
+	remove, err := r.removeURLIndex(ctx, pk, stored)
+	if err != nil {
+		return err
+	}
+	tagWrites, err := r.tagIndexWrites(pk, stored, nil)
+	if err != nil {
+		return err
+	}
+	items = append(append(items, remove...), tagWrites...)
+	_, err = r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if reasons := cancellationReasons(err); reasons != nil {
+		switch {
+		case conditionFailed(reasons[0]) && reasons[0].Item == nil:
+			return domain.ErrLeafNotFound
+		case slices.ContainsFunc(reasons, conditionFailed), transactionConflict(reasons):
+			return domain.ErrVersionConflict
+		}
+	}
+	return err
+}
+
+// 保存済みのLeafのURLの索引を外す書き込み
+// 索引が他のLeafを指している（移行前から重複していた）場合は何もしない
+func (r *LeafDynamoRepository) removeURLIndex(ctx context.Context, pk string, stored *LeafRecord) ([]types.TransactWriteItem, error) {
+	owner, err := r.urlOwner(ctx, pk, stored.URL)
+	if err != nil || owner != stored.ID {
+		return nil, err
+	}
+	return []types.TransactWriteItem{deleteURLIndex(r.TableName, pk, stored.URL, stored.ID)}, nil
 }
 
 // DynamoDB永続化用レコード
 
 type LeafRecord struct {
-	PK       string   `dynamodbav:"pk"`
-	SK       string   `dynamodbav:"sk"`
-	ID       string   `dynamodbav:"id"`
-	Note     string   `dynamodbav:"note"`
-	URL      string   `dynamodbav:"url"`
-	Platform string   `dynamodbav:"platform"`
-	Tags     []string `dynamodbav:"tags"`
-	Read     bool     `dynamodbav:"read"`
-	SyncedAt string   `dynamodbav:"synced_at"`
-}
-
-// EntityをRecordに変換
-func LeafToRecord(l *domain.Leaf) *LeafRecord {
+	PK string `dynamodbav:"pk"`
+	SK string `dynamodbav:"sk"`
+	ID string `dynamodbav:"id"`
+	// タイトル（属性がない既存データはnoteがタイトル）・説明・利用者のメモ
+	Title       string   `dynamodbav:"title,omitempty"`
+	Description string   `dynamodbav:"description,omitempty"`
+	Note        string   `dynamodbav:"note"`
+	URL         string   `dynamodbav:"url"`
+	Platform    string   `dynamodbav:"platform"`
+	Tags        []string `dynamodbav:"tags"`
+	// タグの祖先（aws/lambdaならaws）。子孫のパターン（aws/*）の絞り込みに使う
+	TagPrefixes []string `dynamodbav:"tag_prefixes,omitempty"`
+	// 読書状態から導出した既読（既読の絞り込み・並び替え・タグの件数に使う）
+	Read bool `dynamodbav:"read"`
+	// 読書状態（属性がない既存データはreadから判断する）と遷移した日時
+	Status     string `dynamodbav:"status,omitempty"`
+	StartedAt  string `dynamodbav:"started_at,omitempty"`
+	ReadAt     string `dynamodbav:"read_at,omitempty"`
+	ArchivedAt string `dynamodbav:"archived_at,omitempty"`
+	// 登録・変更日時（属性がない既存データは同期日時で補う）
+	CreatedAt string `dynamodbav:"created_at,omitempty"`
+	UpdatedAt string `dynamodbav:"updated_at,omitempty"`
+	// 同期日時（同期していなければ属性なし）
+	SyncedAt string `dynamodbav:"synced_at,omitempty"`
+	// URLのページから取得した情報（取得していなければ属性なし）
+	SiteName     string `dynamodbav:"site_name,omitempty"`
+	CanonicalURL string `dynamodbav:"canonical_url,omitempty"`
+	ImageURL     string `dynamodbav:"image_url,omitempty"`
+	PublishedAt  string `dynamodbav:"published_at,omitempty"`
+	FetchedAt    string `dynamodbav:"fetched_at,omitempty"`
+	// 並び替え用GSIのソートキー（LeafSortKeyの末尾にIDを付ける）
+	SyncedSort string `dynamodbav:"synced_sort"`
+	TitleSort  string `dynamodbav:"title_sort"`
+	ReadSort   string `dynamodbav:"read_sort"`
+	// 楽観ロック用のバージョン（属性がない既存データは0）
+	Version int `dynamodbav:"version"`
+	// ゴミ箱に移した日時（ゴミ箱になければ属性なし）
+	DeletedAt string `dynamodbav:"deleted_at,omitempty"`
+	// TTLで削除する日時（UNIX秒、ゴミ箱にあり保持期間が有限の場合だけ）
+	ExpiresAt int64 `dynamodbav:"expires_at,omitempty"`
+	// タグの索引の複製が属するタグ（Leaf本体は属性なし）
+	Tag string `dynamodbav:"tag,omitempty"`
+}
+
+// TTLに使う属性
+const ttlAttribute = "expires_at"
+
+// 利用者ごとのパーティションキー
+// すべての読み書きはコンテキストの利用者のパーティションに限定する
+func userPartition(ctx context.Context) (string, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "USER#" + userID.String(), nil
+}
+
+func leafKey(pk, id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+		"sk": &types.AttributeValueMemberS{Value: id},
+	}
+}
+
+// 保存するレコード（バージョンを進め、ゴミ箱のLeafには有効期限を付ける）
+func (r *LeafDynamoRepository) record(pk string, l *domain.Leaf) *LeafRecord {
+	record := LeafToRecord(pk, l)
+	record.Version = l.Version() + 1
+	if l.Trashed() && r.TrashRetention > 0 {
+		record.ExpiresAt = l.DeletedAt().Add(r.TrashRetention).Unix()
+	}
+	return record
+}
+
+// EntityをRecordに変換（pkは所有者のパーティション）
+func LeafToRecord(pk string, l *domain.Leaf) *LeafRecord {
 	tags := make([]string, len(l.Tags()))
+	var prefixes []string
 	for i, t := range l.Tags() {
 		tags[i] = t.String()
+		for _, a := range t.Ancestors() {
+			if !slices.Contains(prefixes, a.String()) {
+				prefixes = append(prefixes, a.String())
+			}
+		}
+	}
+	record := &LeafRecord{
+		PK:          pk,
+		SK:          l.ID().String(),
+		ID:          l.ID().String(),
+		Title:       l.Title(),
+		Description: l.Description(),
+		Note:        l.Note(),
+		URL:         l.URL().String(),
+		Platform:    l.Platform(),
+		Tags:        tags,
+		TagPrefixes: prefixes,
+		Read:        l.Read(),
+		CreatedAt:   storage.FormatTime(l.CreatedAt()),
+		UpdatedAt:   storage.FormatTime(l.UpdatedAt()),
+		SyncedAt:    storage.FormatTime(l.SyncedAt()),
+		SyncedSort:  indexSortKey(l, domain.SortBySyncedAt),
+		TitleSort:   indexSortKey(l, domain.SortByTitle),
+		ReadSort:    indexSortKey(l, domain.SortByRead),
+		Version:     l.Version(),
 	}
-	return &LeafRecord{
-		PK:       "USER#me",
-		SK:       l.ID().String(),
-		ID:       l.ID().String(),
-		Note:     l.Note(),
-		URL:      l.URL().String(),
-		Platform: l.Platform(),
-		Tags:     tags,
-		Read:     l.Read(),
-		SyncedAt: l.SyncedAt().Format(time.RFC3339),
+	reading := l.ReadingState()
+	record.Status = reading.Status.String()
+	record.StartedAt = storage.FormatTime(reading.StartedAt)
+	record.ReadAt = storage.FormatTime(reading.ReadAt)
+	record.ArchivedAt = storage.FormatTime(reading.ArchivedAt)
+	page := l.Page()
+	record.SiteName = page.SiteName
+	record.CanonicalURL = page.CanonicalURL
+	record.ImageURL = page.ImageURL
+	record.PublishedAt = storage.FormatTime(page.PublishedAt)
+	record.FetchedAt = storage.FormatTime(page.FetchedAt)
+	if l.Trashed() {
+		record.DeletedAt = storage.FormatTime(l.DeletedAt())
+	}
+	return record
+}
+
+// 保存済みのバージョンがexpectedであることを表す条件式
+// version属性のない既存データとversion=0の既存データはバージョン0として扱う
+func versionCondition(expected int) (string, map[string]string, map[string]types.AttributeValue) {
+	names := map[string]string{"#version": "version"}
+	if expected == 0 {
+		return "attribute_exists(sk) AND (attribute_not_exists(#version) OR #version = :version)", names, map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: "0"},
+		}
+	}
+	return "#version = :version", names, map[string]types.AttributeValue{
+		":version": &types.AttributeValueMemberN{Value: strconv.Itoa(expected)},
 	}
 }
 
 // RecordをEntityに変換
 func RecordToLeaf(r *LeafRecord) (*domain.Leaf, error) {
-	syncedAt, err := time.Parse(time.RFC3339, r.SyncedAt)
+	syncedAt, err := storage.ParseTime(r.SyncedAt)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := storage.ParseTime(r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := storage.ParseTime(r.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
-	leaf, err := domain.ReconstructLeaf(r.ID, r.Note, r.URL, r.Platform, r.Tags, r.Read, syncedAt)
+	// 登録日時のない既存データは同期日時を登録・変更日時とし、同期以外で登録したLeafの同期日時は消す
+	if r.CreatedAt == "" {
+		createdAt, updatedAt = syncedAt, syncedAt
+		if r.Platform != domain.PlatformQiita {
+			syncedAt = time.Time{}
+		}
+	}
+	deletedAt, err := storage.ParseTime(r.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	reading := domain.ReadingStateFromRead(r.Read)
+	if r.Status != "" {
+		reading.Status = domain.LeafStatus(r.Status)
+	}
+	if reading.StartedAt, err = storage.ParseTime(r.StartedAt); err != nil {
+		return nil, err
+	}
+	if reading.ReadAt, err = storage.ParseTime(r.ReadAt); err != nil {
+		return nil, err
+	}
+	if reading.ArchivedAt, err = storage.ParseTime(r.ArchivedAt); err != nil {
+		return nil, err
+	}
+	page := domain.PageInfo{SiteName: r.SiteName, CanonicalURL: r.CanonicalURL, ImageURL: r.ImageURL}
+	if page.PublishedAt, err = storage.ParseTime(r.PublishedAt); err != nil {
+		return nil, err
+	}
+	if page.FetchedAt, err = storage.ParseTime(r.FetchedAt); err != nil {
+		return nil, err
+	}
+	// タイトルのない既存データはnoteをタイトルとし、同期したLeafのメモは空にする（記事のタイトルが入っていたため）
+	title, note := r.Title, r.Note
+	if title == "" {
+		title = r.Note
+		if r.Platform == domain.PlatformQiita {
+			note = ""
+		}
+	}
+	leaf, err := domain.ReconstructLeaf(r.ID, title, r.Description, note, r.URL, r.Platform, r.Tags, reading, page, createdAt, updatedAt, syncedAt, deletedAt, r.Version)
 	if err != nil {
 		return nil, err
 	}
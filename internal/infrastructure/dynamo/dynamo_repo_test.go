@@ -0,0 +1,61 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/domain/repotest"
+)
+
+// DynamoDB Localのエンドポイント（例: http://localhost:8000）。未設定ならテストを省略する
+const testEndpointEnv = "DYNAMO_TEST_ENDPOINT"
+
+var tableSeq atomic.Int64
+
+// テストごとに作成した空のテーブル（テストの終了時に削除する）
+func newTestTable(t *testing.T) (*dynamodb.Client, string) {
+	t.Helper()
+	endpoint := os.Getenv(testEndpointEnv)
+	if endpoint == "" {
+		t.Skipf("%s が未設定のため、DynamoDB Localでのテストを省略します", testEndpointEnv)
+	}
+	// DynamoDB Localは認証情報を検証しないが、SDKは認証情報を必要とする
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+		t.Setenv("AWS_ACCESS_KEY_ID", "local")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "local")
+	}
+	tableName := fmt.Sprintf("logleaf-test-%d-%d", time.Now().UnixNano(), tableSeq.Add(1))
+	t.Setenv("DYNAMO_ENDPOINT", endpoint)
+	t.Setenv("DYNAMO_TABLE", tableName)
+
+	ctx := context.Background()
+	client, _, err := NewDynamoClientAndTable(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMigrator(client, tableName).Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{TableName: &tableName})
+	})
+	return client, tableName
+}
+
+func TestLeafRepository(t *testing.T) {
+	repotest.TestLeafRepository(t, func(t *testing.T) domain.LeafRepository {
+		return NewLeafDynamoRepository(newTestTable(t))
+	})
+}
+
+func TestTagAliasRepository(t *testing.T) {
+	repotest.TestTagAliasRepository(t, func(t *testing.T) domain.TagAliasRepository {
+		return NewTagAliasDynamoRepository(newTestTable(t))
+	})
+}
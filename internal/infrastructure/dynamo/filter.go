@@ -0,0 +1,95 @@
+package dynamo
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// ListOptionsの絞り込み条件をFilterExpressionに変換する
+// ゴミ箱の条件は常に含まれるのでexprは空にならない
+func buildFilter(opts domain.ListOptions) (expr string, names map[string]string, values map[string]types.AttributeValue) {
+	names = map[string]string{}
+	values = map[string]types.AttributeValue{}
+	var conds []string
+
+	// プラットフォーム（いずれかに一致）
+	if len(opts.Platforms) > 0 {
+		names["#platform"] = "platform"
+		placeholders := make([]string, len(opts.Platforms))
+		for i, p := range opts.Platforms {
+			key := ":platform" + strconv.Itoa(i)
+			placeholders[i] = key
+			values[key] = &types.AttributeValueMemberS{Value: p}
+		}
+		conds = append(conds, "#platform IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	// タグ（any: OR結合 / all: AND結合）
+	// 子孫のパターン（aws/*）は祖先のタグ（tag_prefixes）にawsを含むかで判定する
+	if len(opts.Tags) > 0 {
+		tagConds := make([]string, len(opts.Tags))
+		for i, t := range opts.Tags {
+			key := ":tag" + strconv.Itoa(i)
+			if parent, ok := domain.TagPatternParent(t); ok {
+				names["#tag_prefixes"] = "tag_prefixes"
+				tagConds[i] = "contains(#tag_prefixes, " + key + ")"
+				values[key] = &types.AttributeValueMemberS{Value: parent}
+				continue
+			}
+			names["#tags"] = "tags"
+			tagConds[i] = "contains(#tags, " + key + ")"
+			values[key] = &types.AttributeValueMemberS{Value: t}
+		}
+		sep := " OR "
+		if opts.TagMatch == domain.TagMatchAll {
+			sep = " AND "
+		}
+		conds = append(conds, "("+strings.Join(tagConds, sep)+")")
+	}
+
+	// 既読状態（readは予約語のためプレースホルダ必須）
+	if opts.Read != nil {
+		names["#read"] = "read"
+		values[":read"] = &types.AttributeValueMemberBOOL{Value: *opts.Read}
+		conds = append(conds, "#read = :read")
+	}
+
+	// 読書状態（statusは予約語。属性がない既存データは含まれないので、マイグレーションで補う）
+	if len(opts.Statuses) > 0 {
+		names["#status"] = "status"
+		placeholders := make([]string, len(opts.Statuses))
+		for i, st := range opts.Statuses {
+			key := ":status" + strconv.Itoa(i)
+			placeholders[i] = key
+			values[key] = &types.AttributeValueMemberS{Value: st.String()}
+		}
+		conds = append(conds, "#status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	// ゴミ箱（deleted_atはゴミ箱にあるLeafだけが持つ）
+	names["#deleted_at"] = "deleted_at"
+	if opts.Trashed {
+		conds = append(conds, "attribute_exists(#deleted_at)")
+	} else {
+		conds = append(conds, "attribute_not_exists(#deleted_at)")
+	}
+
+	return strings.Join(conds, " AND "), names, values
+}
+
+// 一覧のLeafが必ず含むタグ（タグの索引のパーティションを読める場合）
+// タグが1つだけの条件か、すべてを含む条件の最初のタグ（子孫のパターンは除く）
+func requiredTag(opts domain.ListOptions) (string, bool) {
+	if len(opts.Tags) > 1 && opts.TagMatch != domain.TagMatchAll {
+		return "", false
+	}
+	for _, t := range opts.Tags {
+		if _, ok := domain.TagPatternParent(t); !ok {
+			return t, true
+		}
+	}
+	return "", false
+}
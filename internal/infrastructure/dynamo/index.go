@@ -0,0 +1,43 @@
+package dynamo
+
+import "github.com/umekikazuya/logleaf/internal/domain"
+
+// 並び替え用のGSI
+// パーティションキーはテーブルと同じpk、射影はALL
+type sortIndex struct {
+	Name    string // インデックス名
+	SortKey string // ソートキー属性（文字列型）
+}
+
+var sortIndexes = map[string]sortIndex{
+	domain.SortBySyncedAt: {Name: "pk-synced_sort-index", SortKey: "synced_sort"}, // 同期していなければ登録日時
+	domain.SortByTitle:    {Name: "pk-title_sort-index", SortKey: "title_sort"},
+	domain.SortByRead:     {Name: "pk-read_sort-index", SortKey: "read_sort"},
+}
+
+// GSIのソートキーの値（並び替えキーの末尾にIDを付ける）
+// DynamoDBは同じソートキーの項目の順を決めないため、IDの順に並べてページの境目で重複・欠落しないようにする
+// 区切りにはどの文字よりも小さい\x00を使い、前方が一致するキー（goとgo 1.22）の順を変えない
+func indexSortKey(l *domain.Leaf, sortBy string) string {
+	return domain.LeafSortKey(l, sortBy) + "\x00" + l.ID().String()
+}
+
+// 並び替え項目に対応するGSIを返す（SortByが空ならテーブル本体を使うのでnil）
+func sortIndexFor(sortBy string) (*sortIndex, error) {
+	if sortBy == "" {
+		return nil, nil
+	}
+	idx, ok := sortIndexes[sortBy]
+	if !ok {
+		return nil, domain.ErrUnsupportedSort
+	}
+	return &idx, nil
+}
+
+// Queryのページングキーに含まれる属性
+func (idx *sortIndex) keyAttributes() []string {
+	if idx == nil {
+		return []string{"pk", "sk"}
+	}
+	return []string{"pk", "sk", idx.SortKey}
+}
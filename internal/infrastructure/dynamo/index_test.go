@@ -0,0 +1,49 @@
+package dynamo
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// GSIのソートキーの辞書順が、並び替えキー→IDの順と一致する
+func TestIndexSortKeyOrder(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var leaves []*domain.Leaf
+	for i, title := range []string{"Go", "go", "Go 1.22", "go!", "Go#", "go\tlang", "golang", "apple", "Apple"} {
+		createdAt := base.Add(time.Duration(i%3) * time.Hour)
+		leaf, err := domain.ReconstructLeaf(string(rune('a'+8-i)), title, "", "", "https://example.com/", "web", nil, domain.ReadingStateFromRead(i%2 == 0), domain.PageInfo{}, createdAt, createdAt, time.Time{}, time.Time{}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, leaf)
+	}
+
+	for _, sortBy := range []string{domain.SortBySyncedAt, domain.SortByTitle, domain.SortByRead} {
+		want := slices.Clone(leaves)
+		slices.SortFunc(want, func(a, b *domain.Leaf) int {
+			return cmp.Or(
+				cmp.Compare(domain.LeafSortKey(a, sortBy), domain.LeafSortKey(b, sortBy)),
+				cmp.Compare(a.ID().String(), b.ID().String()),
+			)
+		})
+		got := slices.Clone(leaves)
+		slices.SortFunc(got, func(a, b *domain.Leaf) int {
+			return cmp.Compare(indexSortKey(a, sortBy), indexSortKey(b, sortBy))
+		})
+		if !slices.Equal(ids(got), ids(want)) {
+			t.Errorf("%s: order = %v, want %v", sortBy, ids(got), ids(want))
+		}
+	}
+}
+
+func ids(leaves []*domain.Leaf) []string {
+	out := make([]string, len(leaves))
+	for i, l := range leaves {
+		out[i] = l.ID().String()
+	}
+	return out
+}
@@ -0,0 +1,259 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// スキーマバージョンを記録するアイテムのキー
+// Leafのパーティション（USER#...）とは衝突しない
+const (
+	schemaPK = "META#schema"
+	schemaSK = "version"
+)
+
+// データのマイグレーション
+// 中断しても再実行できるよう、Upは何度実行しても同じ結果にすること
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, client *dynamodb.Client, tableName string) error
+}
+
+// バージョン順に並べること
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "既存Leafを現在の形式で書き直す（noteをtitleへ、readをstatusへ移し、created_at, updated_at, tag_prefixesと並び替えキーtitle_sort, read_sort, synced_sortをバックフィル）",
+		Up:          rewriteLeafRecords,
+	},
+	{
+		Version:     2,
+		Description: "楽観ロック用のversionを既存Leafに設定",
+		Up:          backfillLeafVersion,
+	},
+	{
+		Version:     3,
+		Description: "URLの一意性を保証する索引を既存Leafから作成",
+		Up:          buildURLIndex,
+	},
+	{
+		Version:     4,
+		Description: "タグの索引と件数を既存Leafから作成",
+		Up:          buildTagIndex,
+	},
+	{
+		Version:     5,
+		Description: "URLの索引を正規化したURLで作り直す",
+		Up:          canonicalizeURLIndex,
+	},
+}
+
+// テーブルを作成し、未適用のマイグレーションを適用する
+type Migrator struct {
+	Client     *dynamodb.Client
+	TableName  string
+	Migrations []Migration
+}
+
+func NewMigrator(client *dynamodb.Client, tableName string) *Migrator {
+	return &Migrator{
+		Client:     client,
+		TableName:  tableName,
+		Migrations: migrations,
+	}
+}
+
+// テーブルを作成・更新し、記録済みのスキーマバージョンより新しいマイグレーションを順に適用する
+// 適用したマイグレーションを返す
+func (m *Migrator) Migrate(ctx context.Context) ([]Migration, error) {
+	if err := EnsureTable(ctx, m.Client, m.TableName); err != nil {
+		return nil, err
+	}
+	current, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, mig := range m.Migrations {
+		if mig.Version <= current {
+			continue
+		}
+		if err := mig.Up(ctx, m.Client, m.TableName); err != nil {
+			return applied, fmt.Errorf("マイグレーション %d (%s) に失敗しました: %w", mig.Version, mig.Description, err)
+		}
+		if err := m.setVersion(ctx, mig.Version); err != nil {
+			return applied, err
+		}
+		current = mig.Version
+		applied = append(applied, mig)
+	}
+	return applied, nil
+}
+
+// 記録済みのスキーマバージョン（未記録なら0）
+func (m *Migrator) CurrentVersion(ctx context.Context) (int, error) {
+	out, err := m.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &m.TableName,
+		Key:            schemaKey(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if out.Item == nil {
+		return 0, nil
+	}
+	var item struct {
+		Version int `dynamodbav:"version"`
+	}
+	if err := attributevalue.UnmarshalMap(out.Item, &item); err != nil {
+		return 0, err
+	}
+	return item.Version, nil
+}
+
+// バージョンは単調増加のみ許可（並行実行で先に進んだ記録を巻き戻さない）
+func (m *Migrator) setVersion(ctx context.Context, version int) error {
+	item := schemaKey()
+	item["version"] = &types.AttributeValueMemberN{Value: strconv.Itoa(version)}
+	_, err := m.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                &m.TableName,
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(pk) OR #version < :v"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":v": &types.AttributeValueMemberN{Value: strconv.Itoa(version)},
+		},
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		return nil
+	}
+	return err
+}
+
+func schemaKey() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: schemaPK},
+		"sk": &types.AttributeValueMemberS{Value: schemaSK},
+	}
+}
+
+// すべてのLeafレコードを現在のLeafToRecordで書き直す
+// 派生属性（並び替えキーなど）の追加・変更はこれで反映できる
+// インデックス用などLeaf以外のアイテムはid属性を持たないので対象外
+// タグの索引の複製はLeafと同じ形式なので、同じように書き直す
+// versionを持たない（0の）Leafはversion=1で書き直す
+func rewriteLeafRecords(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:        &tableName,
+		FilterExpression: aws.String("begins_with(pk, :prefix) AND attribute_exists(id)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: "USER#"},
+		},
+	})
+	var rewritten, skipped int
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, raw := range page.Items {
+			var record LeafRecord
+			if err := attributevalue.UnmarshalMap(raw, &record); err != nil {
+				return err
+			}
+			leaf, err := RecordToLeaf(&record)
+			if err != nil {
+				return fmt.Errorf("Leaf %s を変換できません: %w", record.ID, err)
+			}
+			// 有効期限は保持期間の設定から決まるのでそのまま引き継ぐ
+			next := LeafToRecord(record.PK, leaf)
+			next.ExpiresAt = record.ExpiresAt
+			next.Tag = record.Tag
+			next.Version = max(leaf.Version(), 1)
+			item, err := attributevalue.MarshalMap(next)
+			if err != nil {
+				return err
+			}
+			// 移行中に削除・更新されたLeafは書き換えない
+			// （アプリが書き込んだ時点で最新の形式になっている）
+			cond, names, values := versionCondition(leaf.Version())
+			_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:                 &tableName,
+				Item:                      item,
+				ConditionExpression:       aws.String(cond),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			})
+			var condErr *types.ConditionalCheckFailedException
+			if errors.As(err, &condErr) {
+				skipped++
+				log.Printf("Leaf %s (%s) は移行中に更新・削除されたため書き直しませんでした", record.ID, record.PK)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			rewritten++
+		}
+	}
+	log.Printf("Leafレコードを書き直しました（書き直し: %d件、スキップ: %d件）", rewritten, skipped)
+	return nil
+}
+
+// version属性のないLeafと、version=0のLeafにversion=1を設定する
+func backfillLeafVersion(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	const missing = "(attribute_not_exists(#version) OR #version = :zero)"
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:                &tableName,
+		FilterExpression:         aws.String("begins_with(pk, :prefix) AND attribute_exists(id) AND " + missing),
+		ProjectionExpression:     aws.String("pk, sk"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: "USER#"},
+			":zero":   &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	var updated, skipped int
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, key := range page.Items {
+			_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:                &tableName,
+				Key:                      key,
+				UpdateExpression:         aws.String("SET #version = :one"),
+				ConditionExpression:      aws.String("attribute_exists(sk) AND " + missing),
+				ExpressionAttributeNames: map[string]string{"#version": "version"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":zero": &types.AttributeValueMemberN{Value: "0"},
+					":one":  &types.AttributeValueMemberN{Value: "1"},
+				},
+			})
+			var condErr *types.ConditionalCheckFailedException
+			if errors.As(err, &condErr) {
+				// 移行中にアプリが更新・削除した（バージョンが進んでいる）
+				skipped++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			updated++
+		}
+	}
+	log.Printf("Leafにversionを設定しました（設定: %d件、スキップ: %d件）", updated, skipped)
+	return nil
+}
@@ -0,0 +1,160 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// テーブル・インデックスがACTIVEになるまで待つ最大時間
+const tableActiveTimeout = 10 * time.Minute
+
+// Leafのテーブルをキーと並び替え用のGSIを付けて作成し、expires_atのTTLを有効にする
+// テーブルが既にあれば、不足しているGSIだけを追加する
+func EnsureTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &tableName})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		if err := createTable(ctx, client, tableName); err != nil {
+			return err
+		}
+		return ensureTTL(ctx, client, tableName)
+	}
+	if err != nil {
+		return err
+	}
+	// 既存テーブルに不足しているGSIを追加（UpdateTableは1回に1つしか追加できない）
+	existing := make(map[string]struct{})
+	for _, gsi := range desc.Table.GlobalSecondaryIndexes {
+		existing[aws.ToString(gsi.IndexName)] = struct{}{}
+	}
+	for _, idx := range sortedIndexes() {
+		if _, ok := existing[idx.Name]; ok {
+			continue
+		}
+		_, err := client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:            &tableName,
+			AttributeDefinitions: []types.AttributeDefinition{stringAttribute("pk"), stringAttribute(idx.SortKey)},
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+				{Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName:  aws.String(idx.Name),
+					KeySchema:  indexKeySchema(idx),
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+				}},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("GSI %s の追加に失敗しました: %w", idx.Name, err)
+		}
+		if err := waitForIndexes(ctx, client, tableName); err != nil {
+			return err
+		}
+	}
+	return ensureTTL(ctx, client, tableName)
+}
+
+// ゴミ箱のLeafを保持期間後に削除するTTLを有効にする
+func ensureTTL(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	desc, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: &tableName})
+	if err != nil {
+		return err
+	}
+	if ttl := desc.TimeToLiveDescription; ttl != nil && ttl.TimeToLiveStatus != types.TimeToLiveStatusDisabled {
+		return nil
+	}
+	_, err = client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: &tableName,
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(ttlAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("TTLの有効化に失敗しました: %w", err)
+	}
+	return nil
+}
+
+func createTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	attrs := []types.AttributeDefinition{stringAttribute("pk"), stringAttribute("sk")}
+	var gsis []types.GlobalSecondaryIndex
+	for _, idx := range sortedIndexes() {
+		attrs = append(attrs, stringAttribute(idx.SortKey))
+		gsis = append(gsis, types.GlobalSecondaryIndex{
+			IndexName:  aws.String(idx.Name),
+			KeySchema:  indexKeySchema(idx),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		})
+	}
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            &tableName,
+		AttributeDefinitions: attrs,
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: gsis,
+		BillingMode:            types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		return fmt.Errorf("テーブル %s の作成に失敗しました: %w", tableName, err)
+	}
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: &tableName}, tableActiveTimeout); err != nil {
+		return err
+	}
+	return waitForIndexes(ctx, client, tableName)
+}
+
+// テーブルとすべてのGSIがACTIVEになるまでポーリングする
+func waitForIndexes(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	ctx, cancel := context.WithTimeout(ctx, tableActiveTimeout)
+	defer cancel()
+	for {
+		desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &tableName})
+		if err != nil {
+			return err
+		}
+		active := desc.Table.TableStatus == types.TableStatusActive
+		for _, gsi := range desc.Table.GlobalSecondaryIndexes {
+			if gsi.IndexStatus != types.IndexStatusActive {
+				active = false
+			}
+		}
+		if active {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("テーブル %s がACTIVEになりませんでした: %w", tableName, ctx.Err())
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// インデックス名順に並べたGSI定義（作成順を安定させる）
+func sortedIndexes() []sortIndex {
+	indexes := make([]sortIndex, 0, len(sortIndexes))
+	for _, idx := range sortIndexes {
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+	return indexes
+}
+
+func indexKeySchema(idx sortIndex) []types.KeySchemaElement {
+	return []types.KeySchemaElement{
+		{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String(idx.SortKey), KeyType: types.KeyTypeRange},
+	}
+}
+
+func stringAttribute(name string) types.AttributeDefinition {
+	return types.AttributeDefinition{AttributeName: aws.String(name), AttributeType: types.ScalarAttributeTypeS}
+}
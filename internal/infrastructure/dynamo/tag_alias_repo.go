@@ -0,0 +1,122 @@
+package dynamo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// タグの別名はLeafと同じテーブルに置く
+// pk: <利用者のパーティション>#ALIASES, sk: 別名のSHA-256
+type TagAliasDynamoRepository struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewTagAliasDynamoRepository(client *dynamodb.Client, tableName string) *TagAliasDynamoRepository {
+	return &TagAliasDynamoRepository{
+		Client:    client,
+		TableName: tableName,
+	}
+}
+
+type tagAliasRecord struct {
+	PK    string `dynamodbav:"pk"`
+	SK    string `dynamodbav:"sk"`
+	Alias string `dynamodbav:"alias"`
+	Tag   string `dynamodbav:"tag"`
+}
+
+func aliasPartition(ctx context.Context) (string, error) {
+	pk, err := userPartition(ctx)
+	if err != nil {
+		return "", err
+	}
+	return pk + "#ALIASES", nil
+}
+
+func aliasKey(pk, alias string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+		"sk": &types.AttributeValueMemberS{Value: tagHash(alias)},
+	}
+}
+
+func (r *TagAliasDynamoRepository) ListAliases(ctx context.Context) ([]domain.TagAlias, error) {
+	pk, err := aliasPartition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:              &r.TableName,
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+		},
+	})
+	aliases := []domain.TagAlias{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var records []tagAliasRecord
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &records); err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			alias, err := domain.NewTagAlias(record.Alias, record.Tag)
+			if err != nil {
+				return nil, err
+			}
+			aliases = append(aliases, alias)
+		}
+	}
+	slices.SortFunc(aliases, func(a, b domain.TagAlias) int {
+		return cmp.Compare(a.Alias().String(), b.Alias().String())
+	})
+	return aliases, nil
+}
+
+func (r *TagAliasDynamoRepository) PutAlias(ctx context.Context, alias domain.TagAlias) error {
+	pk, err := aliasPartition(ctx)
+	if err != nil {
+		return err
+	}
+	key := aliasKey(pk, alias.Alias().String())
+	item, err := attributevalue.MarshalMap(tagAliasRecord{
+		PK:    pk,
+		SK:    stringValue(key["sk"]),
+		Alias: alias.Alias().String(),
+		Tag:   alias.Tag().String(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &r.TableName, Item: item})
+	return err
+}
+
+func (r *TagAliasDynamoRepository) DeleteAlias(ctx context.Context, alias string) error {
+	pk, err := aliasPartition(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           &r.TableName,
+		Key:                 aliasKey(pk, alias),
+		ConditionExpression: aws.String("attribute_exists(sk)"),
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		return domain.ErrTagAliasNotFound
+	}
+	return err
+}
@@ -0,0 +1,251 @@
+package dynamo
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// タグの索引
+// タグごとのパーティション（<利用者のパーティション>#TAG#<タグのSHA-256>）にLeafのレコードの複製を置く
+// 複製はLeafと同じ属性を持つので、タグで絞り込んだ一覧も並び替え用GSIで読める
+// 複製だけがtag属性（索引のタグ）を持つ
+
+// タグごとの件数の集計アイテム
+// pk: <利用者のパーティション>#TAGS, sk: タグのSHA-256
+type tagCountRecord struct {
+	PK     string `dynamodbav:"pk"`
+	SK     string `dynamodbav:"sk"`
+	Tag    string `dynamodbav:"tag"`
+	Leaves int    `dynamodbav:"leaf_count"`
+	Unread int    `dynamodbav:"unread_count"`
+}
+
+func tagHash(tag string) string {
+	sum := sha256.Sum256([]byte(tag))
+	return hex.EncodeToString(sum[:])
+}
+
+func tagPartition(pk, tag string) string {
+	return pk + "#TAG#" + tagHash(tag)
+}
+
+func tagCountKey(pk, tag string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk + "#TAGS"},
+		"sk": &types.AttributeValueMemberS{Value: tagHash(tag)},
+	}
+}
+
+// タグの索引に置くLeafの複製
+func tagCopy(record *LeafRecord, tag string) (map[string]types.AttributeValue, error) {
+	copied := *record
+	copied.PK = tagPartition(record.PK, tag)
+	copied.Tag = tag
+	return attributevalue.MarshalMap(copied)
+}
+
+// 件数の増減
+type tagDelta struct {
+	leaves, unread int
+}
+
+// Leafが件数に数えられる分（ゴミ箱のLeafと、存在しないLeafはnilで0件）
+func countOf(record *LeafRecord) tagDelta {
+	if record == nil || record.DeletedAt != "" {
+		return tagDelta{}
+	}
+	if record.Read {
+		return tagDelta{leaves: 1}
+	}
+	return tagDelta{leaves: 1, unread: 1}
+}
+
+// storedからrecordへの書き込みによるタグごとの件数の増減（増減のないタグは含まない）
+// storedがnilなら新規作成、recordがnilなら削除
+func tagDeltas(stored, record *LeafRecord) map[string]tagDelta {
+	deltas := make(map[string]tagDelta)
+	if stored != nil {
+		before := countOf(stored)
+		for _, tag := range stored.Tags {
+			d := deltas[tag]
+			deltas[tag] = tagDelta{leaves: d.leaves - before.leaves, unread: d.unread - before.unread}
+		}
+	}
+	if record != nil {
+		after := countOf(record)
+		for _, tag := range record.Tags {
+			d := deltas[tag]
+			deltas[tag] = tagDelta{leaves: d.leaves + after.leaves, unread: d.unread + after.unread}
+		}
+	}
+	for tag, d := range deltas {
+		if d == (tagDelta{}) {
+			delete(deltas, tag)
+		}
+	}
+	return deltas
+}
+
+// 件数を加算する更新（集計アイテムがなければ作成される）
+func tagCountUpdate(tableName, pk, tag string, d tagDelta) *types.Update {
+	return &types.Update{
+		TableName:                &tableName,
+		Key:                      tagCountKey(pk, tag),
+		UpdateExpression:         aws.String("SET #tag = :tag ADD leaf_count :leaves, unread_count :unread"),
+		ExpressionAttributeNames: map[string]string{"#tag": "tag"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tag":    &types.AttributeValueMemberS{Value: tag},
+			":leaves": &types.AttributeValueMemberN{Value: strconv.Itoa(d.leaves)},
+			":unread": &types.AttributeValueMemberN{Value: strconv.Itoa(d.unread)},
+		},
+	}
+}
+
+// Leafの書き込みに合わせてタグの索引と件数を更新する書き込み
+// 複製は現在のタグの分を書き直し、外れたタグの分を削除する
+func (r *LeafDynamoRepository) tagIndexWrites(pk string, stored, record *LeafRecord) ([]types.TransactWriteItem, error) {
+	var items []types.TransactWriteItem
+	if record != nil {
+		for _, tag := range record.Tags {
+			item, err := tagCopy(record, tag)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, types.TransactWriteItem{Put: &types.Put{TableName: &r.TableName, Item: item}})
+		}
+	}
+	if stored != nil {
+		for _, tag := range stored.Tags {
+			if record != nil && slices.Contains(record.Tags, tag) {
+				continue
+			}
+			items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+				TableName: &r.TableName,
+				Key:       leafKey(tagPartition(pk, tag), stored.ID),
+			}})
+		}
+	}
+	for tag, d := range tagDeltas(stored, record) {
+		items = append(items, types.TransactWriteItem{Update: tagCountUpdate(r.TableName, pk, tag, d)})
+	}
+	return items, nil
+}
+
+// 一括作成したLeafの分だけ件数を加算する（トランザクションではないので1件ずつ）
+func (r *LeafDynamoRepository) addTagCounts(ctx context.Context, pk string, deltas map[string]tagDelta) error {
+	for tag, d := range deltas {
+		update := tagCountUpdate(r.TableName, pk, tag, d)
+		_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 update.TableName,
+			Key:                       update.Key,
+			UpdateExpression:          update.UpdateExpression,
+			ExpressionAttributeNames:  update.ExpressionAttributeNames,
+			ExpressionAttributeValues: update.ExpressionAttributeValues,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *LeafDynamoRepository) ListTags(ctx context.Context) ([]domain.TagCount, error) {
+	pk, err := userPartition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paginator := dynamodb.NewQueryPaginator(r.Client, &dynamodb.QueryInput{
+		TableName:              &r.TableName,
+		KeyConditionExpression: aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk + "#TAGS"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	counts := []domain.TagCount{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var records []tagCountRecord
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &records); err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			// 使われなくなったタグの集計アイテムは0件で残る
+			if record.Leaves > 0 {
+				counts = append(counts, domain.TagCount{Tag: record.Tag, Leaves: record.Leaves, Unread: record.Unread})
+			}
+		}
+	}
+	slices.SortFunc(counts, func(a, b domain.TagCount) int { return cmp.Compare(a.Tag, b.Tag) })
+	return counts, nil
+}
+
+// 既存のLeafからタグの索引と件数を作成する
+// 件数は集計し直した値で上書きするので、何度実行しても同じ結果になる
+func buildTagIndex(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	repo := &LeafDynamoRepository{Client: client, TableName: tableName}
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:                &tableName,
+		FilterExpression:         aws.String("begins_with(pk, :prefix) AND attribute_exists(id) AND attribute_not_exists(#tag)"),
+		ExpressionAttributeNames: map[string]string{"#tag": "tag"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: "USER#"},
+		},
+	})
+	counts := make(map[[2]string]tagDelta) // (利用者のパーティション, タグ) → 件数
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		var writes []types.WriteRequest
+		for _, raw := range page.Items {
+			var record LeafRecord
+			if err := attributevalue.UnmarshalMap(raw, &record); err != nil {
+				return err
+			}
+			for _, tag := range record.Tags {
+				item, err := tagCopy(&record, tag)
+				if err != nil {
+					return err
+				}
+				writes = append(writes, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+				key := [2]string{record.PK, tag}
+				c, d := counts[key], countOf(&record)
+				counts[key] = tagDelta{leaves: c.leaves + d.leaves, unread: c.unread + d.unread}
+			}
+		}
+		if err := repo.batchWrite(ctx, writes); err != nil {
+			return err
+		}
+	}
+	var writes []types.WriteRequest
+	for key, c := range counts {
+		record := tagCountKey(key[0], key[1])
+		item, err := attributevalue.MarshalMap(tagCountRecord{
+			PK:     stringValue(record["pk"]),
+			SK:     stringValue(record["sk"]),
+			Tag:    key[1],
+			Leaves: c.leaves,
+			Unread: c.unread,
+		})
+		if err != nil {
+			return err
+		}
+		writes = append(writes, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+	}
+	return repo.batchWrite(ctx, writes)
+}
@@ -0,0 +1,260 @@
+package dynamo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// URLの一意性を保証する索引アイテム
+// pk: <利用者のパーティション>#URL, sk: 正規化したURLのSHA-256（キー長の上限を避ける）
+// Leafと区別するためid属性は持たない
+type urlIndexRecord struct {
+	PK     string `dynamodbav:"pk"`
+	SK     string `dynamodbav:"sk"`
+	LeafID string `dynamodbav:"leaf_id"`
+	URL    string `dynamodbav:"url"` // 正規化したURL
+	// Leafと同時にTTLで削除されるよう、Leafと同じ有効期限を持つ
+	ExpiresAt int64 `dynamodbav:"expires_at,omitempty"`
+}
+
+// 正規化したURLで引く索引アイテムのキー（正規化前のURLを渡してよい）
+func urlIndexKey(pk, url string) map[string]types.AttributeValue {
+	sum := sha256.Sum256([]byte(domain.CanonicalizeURL(url)))
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk + "#URL"},
+		"sk": &types.AttributeValueMemberS{Value: hex.EncodeToString(sum[:])},
+	}
+}
+
+func urlIndexItem(pk, url, id string, expiresAt int64) (map[string]types.AttributeValue, error) {
+	key := urlIndexKey(pk, url)
+	return attributevalue.MarshalMap(urlIndexRecord{
+		PK:        key["pk"].(*types.AttributeValueMemberS).Value,
+		SK:        key["sk"].(*types.AttributeValueMemberS).Value,
+		LeafID:    id,
+		URL:       domain.CanonicalizeURL(url),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// LeafのURLの索引アイテムを登録する書き込み
+// 他のLeafが登録済みなら失敗し、登録済みの内容を返す
+func putURLIndex(tableName string, record *LeafRecord) (types.TransactWriteItem, error) {
+	item, err := urlIndexItem(record.PK, record.URL, record.ID, record.ExpiresAt)
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{Put: &types.Put{
+		TableName:           &tableName,
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(sk) OR leaf_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: record.ID},
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}}, nil
+}
+
+// 指定したLeafを指しているURLの索引アイテムを削除する書き込み
+func deleteURLIndex(tableName, pk, url, id string) types.TransactWriteItem {
+	return types.TransactWriteItem{Delete: &types.Delete{
+		TableName:           &tableName,
+		Key:                 urlIndexKey(pk, url),
+		ConditionExpression: aws.String("leaf_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: id},
+		},
+	}}
+}
+
+// 登録済みの索引アイテムを*DuplicateURLErrorにする
+func duplicateURLError(item map[string]types.AttributeValue) error {
+	var record urlIndexRecord
+	if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+		return err
+	}
+	return &domain.DuplicateURLError{ExistingID: record.LeafID}
+}
+
+// URLの索引アイテムが指すLeafID（未登録なら空文字）
+func (r *LeafDynamoRepository) urlOwner(ctx context.Context, pk, url string) (string, error) {
+	out, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.TableName,
+		Key:            urlIndexKey(pk, url),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || out.Item == nil {
+		return "", err
+	}
+	var record urlIndexRecord
+	if err := attributevalue.UnmarshalMap(out.Item, &record); err != nil {
+		return "", err
+	}
+	return record.LeafID, nil
+}
+
+func (r *LeafDynamoRepository) FindByURL(ctx context.Context, url string) (*domain.Leaf, error) {
+	pk, err := userPartition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := r.urlOwner(ctx, pk, url)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, domain.ErrLeafNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *LeafDynamoRepository) LookupURLs(ctx context.Context, urls []string) (map[string]string, error) {
+	pk, err := userPartition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 索引は正規化したURLで引き、渡されたURLに戻して返す
+	requested := make(map[string][]string, len(urls))
+	keys := make([]map[string]types.AttributeValue, 0, len(urls))
+	for _, url := range urls {
+		canonical := domain.CanonicalizeURL(url)
+		if _, ok := requested[canonical]; !ok {
+			keys = append(keys, urlIndexKey(pk, canonical))
+		}
+		requested[canonical] = append(requested[canonical], url)
+	}
+	items, err := r.batchGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	var records []urlIndexRecord
+	if err := attributevalue.UnmarshalListOfMaps(items, &records); err != nil {
+		return nil, err
+	}
+	found := make(map[string]string, len(records))
+	for _, record := range records {
+		for _, url := range requested[record.URL] {
+			found[url] = record.LeafID
+		}
+	}
+	return found, nil
+}
+
+// トランザクションがキャンセルされた理由（トランザクション以外のエラーならnil）
+// 条件チェックに失敗した書き込みは、登録済みの内容とともに返る
+func cancellationReasons(err error) []types.CancellationReason {
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return canceled.CancellationReasons
+	}
+	return nil
+}
+
+func conditionFailed(reason types.CancellationReason) bool {
+	return aws.ToString(reason.Code) == "ConditionalCheckFailed"
+}
+
+// 同じ項目への他のトランザクションと衝突した
+func transactionConflict(reasons []types.CancellationReason) bool {
+	return slices.ContainsFunc(reasons, func(reason types.CancellationReason) bool {
+		return aws.ToString(reason.Code) == "TransactionConflict"
+	})
+}
+
+// 既存のLeafにURLの索引アイテムを作成する（タグの索引の複製は対象外）
+// 同じURLのLeafが複数あれば、先に見つかったLeafを登録済みとして扱う
+func buildURLIndex(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:                &tableName,
+		FilterExpression:         aws.String("begins_with(pk, :prefix) AND attribute_exists(id) AND attribute_not_exists(#tag)"),
+		ProjectionExpression:     aws.String("pk, id, #url, expires_at"),
+		ExpressionAttributeNames: map[string]string{"#url": "url", "#tag": "tag"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: "USER#"},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, raw := range page.Items {
+			var record LeafRecord
+			if err := attributevalue.UnmarshalMap(raw, &record); err != nil {
+				return err
+			}
+			item, err := urlIndexItem(record.PK, record.URL, record.ID, record.ExpiresAt)
+			if err != nil {
+				return err
+			}
+			_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:           &tableName,
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(sk)"),
+			})
+			var condErr *types.ConditionalCheckFailedException
+			if err != nil && !errors.As(err, &condErr) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 正規化する前のURLで作成した索引アイテムを、正規化したURLの索引アイテムに置き換える
+// 正規化すると同じになるLeafが複数あれば、先に見つかったLeafを登録済みとして扱う
+func canonicalizeURLIndex(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	if err := buildURLIndex(ctx, client, tableName); err != nil {
+		return err
+	}
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:                &tableName,
+		FilterExpression:         aws.String("begins_with(pk, :prefix) AND attribute_exists(leaf_id)"),
+		ProjectionExpression:     aws.String("pk, sk, leaf_id, #url"),
+		ExpressionAttributeNames: map[string]string{"#url": "url"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: "USER#"},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, raw := range page.Items {
+			var record urlIndexRecord
+			if err := attributevalue.UnmarshalMap(raw, &record); err != nil {
+				return err
+			}
+			if !strings.HasSuffix(record.PK, "#URL") || record.URL == domain.CanonicalizeURL(record.URL) {
+				continue
+			}
+			_, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: &tableName,
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: record.PK},
+					"sk": &types.AttributeValueMemberS{Value: record.SK},
+				},
+				ConditionExpression: aws.String("leaf_id = :id"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":id": &types.AttributeValueMemberS{Value: record.LeafID},
+				},
+			})
+			var condErr *types.ConditionalCheckFailedException
+			if err != nil && !errors.As(err, &condErr) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/storage"
+)
+
+// メモリ上のLeafRepository（並行して使える）
+// Leafは複製を保存・返却し、呼び出し元と状態を共有しない
+type LeafMemoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]*userStore // UserID → 利用者のデータ
+	// ゴミ箱のLeafを保持する期間（0なら無期限）
+	// 期限を過ぎたLeafは次の書き込み時に削除する
+	TrashRetention time.Duration
+}
+
+// 利用者ごとのLeafとURLの索引
+type userStore struct {
+	leaves map[string]*domain.Leaf // LeafID → Leaf
+	urls   map[string]string       // 正規化したURL → LeafID
+}
+
+func NewLeafMemoryRepository() *LeafMemoryRepository {
+	return &LeafMemoryRepository{
+		users: make(map[string]*userStore),
+	}
+}
+
+// コンテキストの利用者のデータ（書き込み時は必要に応じて作成）
+// 呼び出し側でロックを取得していること
+func (r *LeafMemoryRepository) userStore(ctx context.Context, create bool) (*userStore, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := r.users[userID.String()]
+	if !ok {
+		store = &userStore{
+			leaves: make(map[string]*domain.Leaf),
+			urls:   make(map[string]string),
+		}
+		if create {
+			r.users[userID.String()] = store
+		}
+	}
+	return store, nil
+}
+
+// 書き込み対象の利用者のデータ（保持期間を過ぎたゴミ箱のLeafは削除しておく）
+// 呼び出し側で書き込みロックを取得していること
+func (r *LeafMemoryRepository) writableStore(ctx context.Context, create bool) (*userStore, error) {
+	store, err := r.userStore(ctx, create)
+	if err != nil {
+		return nil, err
+	}
+	if r.TrashRetention > 0 {
+		cutoff := time.Now().Add(-r.TrashRetention)
+		for id, leaf := range store.leaves {
+			if leaf.Trashed() && !leaf.DeletedAt().After(cutoff) {
+				delete(store.urls, leaf.URL().Canonical())
+				delete(store.leaves, id)
+			}
+		}
+	}
+	return store, nil
+}
+
+func (r *LeafMemoryRepository) Get(ctx context.Context, id string) (*domain.Leaf, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	store, err := r.userStore(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	leaf, ok := store.leaves[id]
+	if !ok {
+		return nil, domain.ErrLeafNotFound
+	}
+	return leaf.Clone(), nil
+}
+
+func (r *LeafMemoryRepository) FindByURL(ctx context.Context, url string) (*domain.Leaf, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	store, err := r.userStore(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := store.urls[domain.CanonicalizeURL(url)]
+	if !ok {
+		return nil, domain.ErrLeafNotFound
+	}
+	return store.leaves[id].Clone(), nil
+}
+
+func (r *LeafMemoryRepository) LookupURLs(ctx context.Context, urls []string) (map[string]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	store, err := r.userStore(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]string)
+	for _, url := range urls {
+		if id, ok := store.urls[domain.CanonicalizeURL(url)]; ok {
+			found[url] = id
+		}
+	}
+	return found, nil
+}
+
+func (r *LeafMemoryRepository) List(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, string, error) {
+	if !domain.IsSupportedSort(opts.SortBy) {
+		return nil, "", domain.ErrUnsupportedSort
+	}
+	r.mu.RLock()
+	store, err := r.userStore(ctx, false)
+	if err != nil {
+		r.mu.RUnlock()
+		return nil, "", err
+	}
+	entries := make([]entry, 0, len(store.leaves))
+	for _, leaf := range store.leaves {
+		if opts.Match(leaf) {
+			entries = append(entries, entry{key: domain.LeafSortKey(leaf, opts.SortBy), leaf: leaf.Clone()})
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		c := a.compare(b.key, b.leaf.ID().String())
+		if opts.SortDesc {
+			return -c
+		}
+		return c
+	})
+
+	// カーソルが指す位置の直後から返す
+	start := 0
+	if opts.Cursor != "" {
+		cur, err := storage.DecodeCursor(opts.Cursor, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		start, _ = slices.BinarySearchFunc(entries, cur, func(e entry, c storage.Cursor) int {
+			if opts.SortDesc {
+				return -e.compare(c.Key, c.ID)
+			}
+			return e.compare(c.Key, c.ID)
+		})
+		if start < len(entries) && entries[start].leaf.ID().String() == cur.ID {
+			start++
+		}
+	}
+	entries = entries[start:]
+
+	next := ""
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+		last := entries[len(entries)-1]
+		next = storage.EncodeCursor(storage.Cursor{SortBy: opts.SortBy, Desc: opts.SortDesc, Key: last.key, ID: last.leaf.ID().String()})
+	}
+	result := make([]domain.Leaf, len(entries))
+	for i, e := range entries {
+		result[i] = *e.leaf
+	}
+	return result, next, nil
+}
+
+func (r *LeafMemoryRepository) Put(ctx context.Context, leaf *domain.Leaf) (*domain.Leaf, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	store, err := r.writableStore(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.insert(leaf); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+func (r *LeafMemoryRepository) PutMany(ctx context.Context, leaves []*domain.Leaf) ([]*domain.Leaf, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	store, err := r.writableStore(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	var stored []*domain.Leaf
+	for _, leaf := range leaves {
+		var dupErr *domain.DuplicateURLError
+		switch err := store.insert(leaf); {
+		case errors.As(err, &dupErr), errors.Is(err, domain.ErrVersionConflict):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		stored = append(stored, leaf)
+	}
+	return stored, nil
+}
+
+// 新しいLeafを追加する（IDかURLが登録済みなら追加しない）
+func (s *userStore) insert(leaf *domain.Leaf) error {
+	if existing, ok := s.urls[leaf.URL().Canonical()]; ok {
+		return &domain.DuplicateURLError{ExistingID: existing}
+	}
+	if _, exists := s.leaves[leaf.ID().String()]; exists {
+		return domain.ErrVersionConflict
+	}
+	leaf.IncrementVersion()
+	s.leaves[leaf.ID().String()] = leaf.Clone()
+	s.urls[leaf.URL().Canonical()] = leaf.ID().String()
+	return nil
+}
+
+func (r *LeafMemoryRepository) Update(ctx context.Context, update *domain.Leaf) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	store, err := r.writableStore(ctx, false)
+	if err != nil {
+		return err
+	}
+	stored, ok := store.leaves[update.ID().String()]
+	if !ok {
+		return domain.ErrLeafNotFound
+	}
+	if stored.Version() != update.Version() {
+		return domain.ErrVersionConflict
+	}
+	if existing, ok := store.urls[update.URL().Canonical()]; ok && existing != update.ID().String() {
+		return &domain.DuplicateURLError{ExistingID: existing}
+	}
+	update.IncrementVersion()
+	delete(store.urls, stored.URL().Canonical())
+	store.leaves[update.ID().String()] = update.Clone()
+	store.urls[update.URL().Canonical()] = update.ID().String()
+	return nil
+}
+
+func (r *LeafMemoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	store, err := r.writableStore(ctx, false)
+	if err != nil {
+		return err
+	}
+	leaf, ok := store.leaves[id]
+	if !ok {
+		return domain.ErrLeafNotFound
+	}
+	delete(store.urls, leaf.URL().Canonical())
+	delete(store.leaves, id)
+	return nil
+}
+
+func (r *LeafMemoryRepository) ListTags(ctx context.Context) ([]domain.TagCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	store, err := r.userStore(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]*domain.TagCount)
+	for _, leaf := range store.leaves {
+		if leaf.Trashed() {
+			continue
+		}
+		for _, t := range leaf.Tags() {
+			c, ok := counts[t.String()]
+			if !ok {
+				c = &domain.TagCount{Tag: t.String()}
+				counts[t.String()] = c
+			}
+			c.Leaves++
+			if !leaf.Read() {
+				c.Unread++
+			}
+		}
+	}
+	result := make([]domain.TagCount, 0, len(counts))
+	for _, c := range counts {
+		result = append(result, *c)
+	}
+	slices.SortFunc(result, func(a, b domain.TagCount) int { return cmp.Compare(a.Tag, b.Tag) })
+	return result, nil
+}
+
+// 並び替え中のLeafと、その並び替えキー
+type entry struct {
+	key  string
+	leaf *domain.Leaf
+}
+
+// 並び替えキー→IDの順で比較（昇順、SortByが空ならキーは常に空でID順になる）
+func (e entry) compare(key, id string) int {
+	return cmp.Or(cmp.Compare(e.key, key), cmp.Compare(e.leaf.ID().String(), id))
+}
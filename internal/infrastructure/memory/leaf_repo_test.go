@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/domain/repotest"
+)
+
+func TestLeafRepository(t *testing.T) {
+	repotest.TestLeafRepository(t, func(t *testing.T) domain.LeafRepository {
+		return NewLeafMemoryRepository()
+	})
+}
+
+func TestTagAliasRepository(t *testing.T) {
+	repotest.TestTagAliasRepository(t, func(t *testing.T) domain.TagAliasRepository {
+		return NewTagAliasMemoryRepository()
+	})
+}
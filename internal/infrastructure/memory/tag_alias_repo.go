@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// メモリ上のTagAliasRepository（並行して使える）
+type TagAliasMemoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]map[string]domain.TagAlias // UserID → 別名 → 別名の定義
+}
+
+func NewTagAliasMemoryRepository() *TagAliasMemoryRepository {
+	return &TagAliasMemoryRepository{
+		users: make(map[string]map[string]domain.TagAlias),
+	}
+}
+
+func (r *TagAliasMemoryRepository) ListAliases(ctx context.Context) ([]domain.TagAlias, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	aliases := r.users[userID.String()]
+	result := make([]domain.TagAlias, 0, len(aliases))
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		result = append(result, aliases[name])
+	}
+	return result, nil
+}
+
+func (r *TagAliasMemoryRepository) PutAlias(ctx context.Context, alias domain.TagAlias) error {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	aliases, ok := r.users[userID.String()]
+	if !ok {
+		aliases = make(map[string]domain.TagAlias)
+		r.users[userID.String()] = aliases
+	}
+	aliases[alias.Alias().String()] = alias
+	return nil
+}
+
+func (r *TagAliasMemoryRepository) DeleteAlias(ctx context.Context, alias string) error {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	aliases := r.users[userID.String()]
+	if _, ok := aliases[alias]; !ok {
+		return domain.ErrTagAliasNotFound
+	}
+	delete(aliases, alias)
+	return nil
+}
@@ -0,0 +1,144 @@
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// リダイレクトをたどる最大回数
+const maxRedirects = 5
+
+var (
+	ErrBlockedAddress   = errors.New("内部ネットワークのアドレスには接続できません。")
+	ErrUnsupportedURL   = errors.New("http・https以外のURLや、認証情報を含むURLには接続できません。")
+	ErrTooManyRedirects = errors.New("リダイレクトが多すぎます。")
+)
+
+// 公開されたインターネット上のアドレスとして扱わないネットワーク
+// ループバック・プライベート・リンクローカル（クラウドのメタデータサービスを含む）などの
+// net/netipの判定に含まれないものを補う
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // このネットワーク
+	netip.MustParsePrefix("100.64.0.0/10"),  // キャリアグレードNAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETFプロトコル割り当て
+	netip.MustParsePrefix("198.18.0.0/15"),  // ベンチマーク
+	netip.MustParsePrefix("240.0.0.0/4"),    // 予約済み（ブロードキャストを含む）
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64（内部のIPv4アドレスを埋め込める）
+	netip.MustParsePrefix("64:ff9b:1::/48"), // ローカル用のIPv4/IPv6変換
+	netip.MustParsePrefix("2001:db8::/32"),  // 文書用
+	netip.MustParsePrefix("2002::/16"),      // 6to4（内部のIPv4アドレスを埋め込める）
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("fec0::/10"),      // サイトローカル（廃止）
+}
+
+// サーバーから外部のURLに接続するときの接続先を、公開されたアドレスに制限する
+// 接続する直前に名前解決後のアドレスを検査するため、DNSの応答の差し替えやリダイレクトでも内部に接続しない
+type Guard struct {
+	allowed []netip.Prefix // 例外として接続を許すネットワーク
+}
+
+// allowedに含まれるアドレスは、内部のアドレスでも接続を許す（開発環境やテスト用）
+func New(allowed ...netip.Prefix) *Guard {
+	return &Guard{allowed: allowed}
+}
+
+// "10.0.0.0/8,192.168.1.10"のようなカンマ区切りのネットワークとアドレスを解析する
+func ParsePrefixes(raw string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range strings.Split(raw, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("ネットワークの形式が不正です: %s", value)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// 接続してよいアドレスか
+func (g *Guard) CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if slices.ContainsFunc(g.allowed, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+		return nil
+	}
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		slices.ContainsFunc(blockedPrefixes, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+		return fmt.Errorf("%w (%s)", ErrBlockedAddress, addr)
+	}
+	return nil
+}
+
+// 接続してよいURLか（スキーム・認証情報と、ホスト名がIPアドレスならそのアドレスを検査する）
+// ホスト名の名前解決後の検査は接続時に行う
+func (g *Guard) CheckURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || u.Hostname() == "" {
+		return ErrUnsupportedURL
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		return g.CheckAddr(addr)
+	}
+	return nil
+}
+
+// net.Dialer.Controlに設定し、名前解決後の接続先のアドレスを検査する
+func (g *Guard) Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w (%s)", ErrBlockedAddress, address)
+	}
+	return g.CheckAddr(addrPort.Addr())
+}
+
+// 接続先を制限したHTTPクライアント
+// プロキシは使わず（プロキシ経由では接続先を検査できないため）、リダイレクト先のURLも検査する
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: g.Control}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &guardedTransport{guard: g, next: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return g.CheckURL(req.URL)
+		},
+	}
+}
+
+// リクエストごとにURLを検査してから送る
+type guardedTransport struct {
+	guard *Guard
+	next  http.RoundTripper
+}
+
+func (t *guardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.guard.CheckURL(req.URL); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,261 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// 項目ごとの重み（タイトルとタグに一致したLeafを上位にする）
+const (
+	weightTitle       = 3.0
+	weightTags        = 2.0
+	weightDescription = 1.0
+	weightNote        = 1.0
+	weightURL         = 0.5
+)
+
+// 検索語で始まる語に一致したときの重み（完全に一致した語より下げる）
+const prefixWeight = 0.5
+
+// BM25のパラメータ
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// メモリ上に持つ利用者ごとの転置索引（domain.SearchIndexの実装）
+// 最初の検索で保存済みのLeafから作成し、索引を通したリポジトリへの書き込みで更新する
+// 索引はプロセスごとに持つため、他のプロセス（同期バッチなど）の書き込みは反映されない
+// TTLを過ぎた索引は作成前として扱い、次の検索で作り直す
+type Index struct {
+	mu    sync.Mutex
+	users map[string]*userIndex // UserID → 利用者の索引
+	// 索引を作り直すまでの時間（0なら作り直さない）
+	TTL time.Duration
+}
+
+// 利用者ごとの転置索引
+type userIndex struct {
+	mu          sync.RWMutex
+	built       bool
+	builtAt     time.Time
+	docs        map[string]*document           // LeafID → 文書
+	postings    map[string]map[string]struct{} // 語 → 語を含むLeafID
+	totalLength float64
+}
+
+// 索引に登録したLeaf
+type document struct {
+	version int                // 索引に登録したLeafのバージョン（古い内容で上書きしないため）
+	terms   map[string]float64 // 語 → 項目の重みを掛けた出現回数
+	length  float64            // 項目の重みを掛けた語数
+}
+
+func NewIndex() *Index {
+	return &Index{users: make(map[string]*userIndex)}
+}
+
+// コンテキストの利用者の索引（なければ作成する）
+func (x *Index) user(ctx context.Context) (*userIndex, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	u, ok := x.users[userID.String()]
+	if !ok {
+		u = &userIndex{}
+		u.reset()
+		x.users[userID.String()] = u
+	}
+	return u, nil
+}
+
+func (x *Index) Built(ctx context.Context) bool {
+	u, err := x.user(ctx)
+	if err != nil {
+		return false
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.built && (x.TTL <= 0 || time.Since(u.builtAt) < x.TTL)
+}
+
+func (x *Index) Rebuild(ctx context.Context, load func() ([]*domain.Leaf, error)) error {
+	u, err := x.user(ctx)
+	if err != nil {
+		return err
+	}
+	// 読み込みの間はロックを保持し、並行する追加・削除を作り直した後の索引に反映させる
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	leaves, err := load()
+	if err != nil {
+		return err
+	}
+	u.reset()
+	for _, leaf := range leaves {
+		u.put(leaf)
+	}
+	u.built = true
+	u.builtAt = time.Now()
+	return nil
+}
+
+func (x *Index) Index(ctx context.Context, leaf *domain.Leaf) {
+	u, err := x.user(ctx)
+	if err != nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.built {
+		return
+	}
+	if doc, ok := u.docs[leaf.ID().String()]; ok && doc.version > leaf.Version() {
+		return
+	}
+	u.remove(leaf.ID().String())
+	if !leaf.Trashed() {
+		u.put(leaf)
+	}
+}
+
+func (x *Index) Remove(ctx context.Context, id string) {
+	u, err := x.user(ctx)
+	if err != nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.built {
+		u.remove(id)
+	}
+}
+
+func (x *Index) Search(ctx context.Context, query string, limit int) ([]domain.SearchHit, error) {
+	u, err := x.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.search(query, limit), nil
+}
+
+func (u *userIndex) reset() {
+	u.built = false
+	u.docs = make(map[string]*document)
+	u.postings = make(map[string]map[string]struct{})
+	u.totalLength = 0
+}
+
+func (u *userIndex) put(leaf *domain.Leaf) {
+	doc := &document{version: leaf.Version(), terms: make(map[string]float64)}
+	add := func(text string, weight float64) {
+		for _, token := range tokenize(text) {
+			doc.terms[token] += weight
+			doc.length += weight
+		}
+	}
+	add(leaf.Title(), weightTitle)
+	add(leaf.Description(), weightDescription)
+	add(leaf.Note(), weightNote)
+	add(leaf.URL().String(), weightURL)
+	for _, tag := range leaf.Tags() {
+		add(tag.String(), weightTags)
+	}
+	id := leaf.ID().String()
+	u.docs[id] = doc
+	u.totalLength += doc.length
+	for term := range doc.terms {
+		if u.postings[term] == nil {
+			u.postings[term] = make(map[string]struct{})
+		}
+		u.postings[term][id] = struct{}{}
+	}
+}
+
+func (u *userIndex) remove(id string) {
+	doc, ok := u.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(u.postings[term], id)
+		if len(u.postings[term]) == 0 {
+			delete(u.postings, term)
+		}
+	}
+	u.totalLength -= doc.length
+	delete(u.docs, id)
+}
+
+// 検索語の語をすべて含むLeafをBM25のスコアで並べる
+// 語はその語で始まる語にも一致する（"dynamo"は"dynamodb"にも一致する）
+// 日本語1文字の語はその文字で終わるbigramにも一致する（"設"は"設計"にも"建設"にも一致する）
+func (u *userIndex) search(query string, limit int) []domain.SearchHit {
+	terms := tokenize(query)
+	slices.Sort(terms)
+	terms = slices.Compact(terms)
+	if len(terms) == 0 || len(u.docs) == 0 {
+		return []domain.SearchHit{}
+	}
+	n := float64(len(u.docs))
+	avgLength := u.totalLength / n
+	scores := make(map[string]float64)
+	for i, term := range terms {
+		// Leafごとの出現回数（前方一致した語は重みを下げる）
+		unigram := isCJKUnigram(term)
+		tf := make(map[string]float64)
+		for indexed, ids := range u.postings {
+			weight := 1.0
+			if indexed != term {
+				if !strings.HasPrefix(indexed, term) && !(unigram && strings.HasSuffix(indexed, term)) {
+					continue
+				}
+				weight = prefixWeight
+			}
+			for id := range ids {
+				tf[id] += weight * u.docs[id].terms[indexed]
+			}
+		}
+		df := float64(len(tf))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		next := make(map[string]float64, len(tf))
+		for id, f := range tf {
+			// 前の語をすべて含むLeafだけを残す
+			if _, ok := scores[id]; i > 0 && !ok {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*u.docs[id].length/avgLength)
+			next[id] = scores[id] + idf*f*(bm25K1+1)/(f+norm)
+		}
+		scores = next
+		if len(scores) == 0 {
+			break
+		}
+	}
+	hits := make([]domain.SearchHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, domain.SearchHit{LeafID: id, Score: score})
+	}
+	slices.SortFunc(hits, func(a, b domain.SearchHit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.LeafID, b.LeafID)
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
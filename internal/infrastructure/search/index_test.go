@@ -0,0 +1,323 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+func userContext(t *testing.T, user string) context.Context {
+	t.Helper()
+	id, err := domain.NewUserID(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return domain.WithUserID(context.Background(), id)
+}
+
+// テスト用のLeaf
+type leafSpec struct {
+	id, title, description, note, url string
+	tags                              []string
+	trashed                           bool
+	version                           int
+}
+
+func newLeaf(t *testing.T, s leafSpec) *domain.Leaf {
+	t.Helper()
+	if s.url == "" {
+		s.url = "https://example.com/" + s.id
+	}
+	if s.version == 0 {
+		s.version = 1
+	}
+	var deletedAt time.Time
+	if s.trashed {
+		deletedAt = time.Now()
+	}
+	now := time.Now()
+	leaf, err := domain.ReconstructLeaf(s.id, s.title, s.description, s.note, s.url, domain.PlatformWeb, s.tags,
+		domain.ReadingStateFromRead(false), domain.PageInfo{}, now, now, time.Time{}, deletedAt, s.version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf
+}
+
+// 索引をleavesで作成する
+func buildIndex(t *testing.T, ctx context.Context, x *Index, leaves ...*domain.Leaf) {
+	t.Helper()
+	if err := x.Rebuild(ctx, func() ([]*domain.Leaf, error) { return leaves, nil }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hitIDs(t *testing.T, ctx context.Context, x *Index, query string, limit int) []string {
+	t.Helper()
+	hits, err := x.Search(ctx, query, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.LeafID
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	ctx := userContext(t, "alice")
+	x := NewIndex()
+	buildIndex(t, ctx, x,
+		newLeaf(t, leafSpec{id: "title", title: "DynamoDBの設計"}),
+		newLeaf(t, leafSpec{id: "tag", title: "NoSQL入門", tags: []string{"dynamodb"}}),
+		newLeaf(t, leafSpec{id: "description", title: "AWSの記事", description: "DynamoDBのテーブル設計について"}),
+		newLeaf(t, leafSpec{id: "note", title: "メモ", note: "あとでDynamoDBを試す"}),
+		newLeaf(t, leafSpec{id: "url", title: "リンク", url: "https://dynamodb.example.com/"}),
+		newLeaf(t, leafSpec{id: "prefix", title: "DynamoDBStreamsの使い方"}),
+		newLeaf(t, leafSpec{id: "other", title: "Goの並行処理", description: "goroutineとchannel"}),
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{"件数を制限する", "dynamodb", 2, []string{"title", "tag"}},
+		{"すべての語を含むLeafだけ", "dynamodb 設計", 0, []string{"title", "description"}},
+		{"日本語の語句", "並行処理", 0, []string{"other"}},
+		{"大文字・全角をそろえる", "ＧＯＲＯＵＴＩＮＥ", 0, []string{"other"}},
+		{"前方一致", "gorout", 0, []string{"other"}},
+		{"一致しない", "kubernetes", 0, []string{}},
+		{"語を含まない", "、。", 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hitIDs(t, ctx, x, tt.query, tt.limit); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// 一致した項目の重み（タイトル > タグ > 説明・メモ > URL）と前方一致で並べる
+// 文書の長さをそろえるため、どのLeafも各項目に1語ずつ持つ
+func TestIndexFieldWeights(t *testing.T) {
+	ctx := userContext(t, "alice")
+	x := NewIndex()
+	filler := func(id string) leafSpec {
+		return leafSpec{id: id, title: "filler", description: "filler", note: "filler", url: "https://filler.example.com/", tags: []string{"filler"}}
+	}
+	title, tag, description, note, url, prefix := filler("title"), filler("tag"), filler("description"), filler("note"), filler("url"), filler("prefix")
+	title.title = "dynamodb"
+	tag.tags = []string{"dynamodb"}
+	description.description = "dynamodb"
+	note.note = "dynamodb"
+	url.url = "https://dynamodb.example.com/"
+	prefix.title = "dynamodbstreams"
+	var leaves []*domain.Leaf
+	for _, s := range []leafSpec{url, prefix, note, description, tag, title, filler("other")} {
+		leaves = append(leaves, newLeaf(t, s))
+	}
+	buildIndex(t, ctx, x, leaves...)
+
+	// 説明とメモは同じ重みなのでIDの順
+	want := []string{"title", "tag", "prefix", "description", "note", "url"}
+	if got := hitIDs(t, ctx, x, "dynamodb", 0); !slices.Equal(got, want) {
+		t.Errorf("Search(dynamodb) = %v, want %v", got, want)
+	}
+}
+
+// 日本語1文字の検索語は、その文字を前後どちらに含むbigramにも一致する
+func TestIndexSingleCJKCharacter(t *testing.T) {
+	ctx := userContext(t, "alice")
+	x := NewIndex()
+	buildIndex(t, ctx, x,
+		newLeaf(t, leafSpec{id: "prefix", title: "設計の基本"}),
+		newLeaf(t, leafSpec{id: "suffix", title: "大規模な建設"}),
+		newLeaf(t, leafSpec{id: "single", title: "設"}),
+		newLeaf(t, leafSpec{id: "other", title: "実装の基本"}),
+		newLeaf(t, leafSpec{id: "latin", title: "design"}),
+	)
+
+	if got := hitIDs(t, ctx, x, "設", 0); !slices.Equal(sorted(got), []string{"prefix", "single", "suffix"}) {
+		t.Errorf("Search(設) = %v, want prefix, single, suffix", got)
+	}
+	// 完全に一致した語を上位にする
+	if got := hitIDs(t, ctx, x, "設", 0); len(got) == 0 || got[0] != "single" {
+		t.Errorf("Search(設) = %v, want single first", got)
+	}
+	// 英数字1文字は前方一致のまま
+	if got := hitIDs(t, ctx, x, "n", 0); len(got) != 0 {
+		t.Errorf("Search(n) = %v, want no hits", got)
+	}
+}
+
+func sorted(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
+
+func TestIndexBM25(t *testing.T) {
+	ctx := userContext(t, "alice")
+	x := NewIndex()
+	buildIndex(t, ctx, x,
+		// 同じ重みの項目では、語が多く出現する文書ほど上位
+		newLeaf(t, leafSpec{id: "many", title: "lambda", description: "lambda lambda lambda"}),
+		newLeaf(t, leafSpec{id: "once", title: "lambda", description: "serverless"}),
+		// 同じ出現回数なら、短い文書ほど上位
+		newLeaf(t, leafSpec{id: "short", title: "s3"}),
+		newLeaf(t, leafSpec{id: "long", title: "s3", description: "bucket object storage lifecycle versioning replication"}),
+		// 多くの文書に出現する語より、まれな語に一致した文書が上位
+		newLeaf(t, leafSpec{id: "common1", title: "aws", description: "common"}),
+		newLeaf(t, leafSpec{id: "common2", title: "aws", description: "common"}),
+		newLeaf(t, leafSpec{id: "rare", title: "aws", description: "rare"}),
+	)
+	for query, want := range map[string][]string{
+		"lambda": {"many", "once"},
+		"s3":     {"short", "long"},
+	} {
+		if got := hitIDs(t, ctx, x, query, 0); !slices.Equal(got, want) {
+			t.Errorf("Search(%q) = %v, want %v", query, got, want)
+		}
+	}
+	hits, err := x.Search(ctx, "common rare", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("Search(common rare) = %v, want none", hits)
+	}
+	common, _ := x.Search(ctx, "common", 1)
+	rare, _ := x.Search(ctx, "rare", 1)
+	if len(common) != 1 || len(rare) != 1 || rare[0].Score <= common[0].Score {
+		t.Errorf("score(rare) = %v, score(common) = %v, want rare > common", rare, common)
+	}
+}
+
+func TestIndexMaintenance(t *testing.T) {
+	ctx := userContext(t, "alice")
+	x := NewIndex()
+
+	// 作成前の追加は無視する（最初の検索で作り直すため）
+	x.Index(ctx, newLeaf(t, leafSpec{id: "early", title: "kubernetes"}))
+	if x.Built(ctx) {
+		t.Fatal("Built before Rebuild = true")
+	}
+	buildIndex(t, ctx, x, newLeaf(t, leafSpec{id: "a", title: "terraform"}))
+	if !x.Built(ctx) {
+		t.Fatal("Built after Rebuild = false")
+	}
+	if got := hitIDs(t, ctx, x, "kubernetes", 0); len(got) != 0 {
+		t.Errorf("leaf indexed before build = %v", got)
+	}
+
+	// 追加
+	x.Index(ctx, newLeaf(t, leafSpec{id: "b", title: "terraform modules"}))
+	if got := hitIDs(t, ctx, x, "terraform", 0); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("after add = %v", got)
+	}
+
+	// 更新すると古い語では見つからない
+	x.Index(ctx, newLeaf(t, leafSpec{id: "a", title: "pulumi", version: 2}))
+	if got := hitIDs(t, ctx, x, "terraform", 0); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("after update, terraform = %v", got)
+	}
+	if got := hitIDs(t, ctx, x, "pulumi", 0); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("after update, pulumi = %v", got)
+	}
+
+	// 古いバージョンで上書きしない
+	x.Index(ctx, newLeaf(t, leafSpec{id: "a", title: "terraform", version: 1}))
+	if got := hitIDs(t, ctx, x, "pulumi", 0); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("after stale update, pulumi = %v", got)
+	}
+
+	// ゴミ箱に移すと索引から外れ、復元すると戻る
+	x.Index(ctx, newLeaf(t, leafSpec{id: "b", title: "terraform modules", trashed: true, version: 2}))
+	if got := hitIDs(t, ctx, x, "terraform", 0); len(got) != 0 {
+		t.Errorf("after trash = %v", got)
+	}
+	x.Index(ctx, newLeaf(t, leafSpec{id: "b", title: "terraform modules", version: 3}))
+	if got := hitIDs(t, ctx, x, "terraform", 0); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("after restore = %v", got)
+	}
+
+	// 削除
+	x.Remove(ctx, "b")
+	x.Remove(ctx, "missing")
+	if got := hitIDs(t, ctx, x, "terraform", 0); len(got) != 0 {
+		t.Errorf("after remove = %v", got)
+	}
+	u, _ := x.user(ctx)
+	if len(u.docs) != 1 || u.postings["terraform"] != nil || u.postings["modules"] != nil {
+		t.Errorf("postings after remove = %v", u.postings)
+	}
+	if want := u.docs["a"].length; u.totalLength != want {
+		t.Errorf("totalLength = %v, want %v", u.totalLength, want)
+	}
+
+	// 作り直すと、索引を通さずに書き込まれた内容になる
+	buildIndex(t, ctx, x, newLeaf(t, leafSpec{id: "c", title: "ansible"}))
+	if got := hitIDs(t, ctx, x, "pulumi", 0); len(got) != 0 {
+		t.Errorf("after rebuild, pulumi = %v", got)
+	}
+	if got := hitIDs(t, ctx, x, "ansible", 0); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("after rebuild, ansible = %v", got)
+	}
+}
+
+func TestIndexUserIsolation(t *testing.T) {
+	alice, bob := userContext(t, "alice"), userContext(t, "bob")
+	x := NewIndex()
+	buildIndex(t, alice, x, newLeaf(t, leafSpec{id: "a", title: "secret"}))
+	if x.Built(bob) {
+		t.Error("Built(bob) = true after alice's rebuild")
+	}
+	buildIndex(t, bob, x)
+	if got := hitIDs(t, bob, x, "secret", 0); len(got) != 0 {
+		t.Errorf("bob sees %v", got)
+	}
+	if _, err := x.Search(context.Background(), "secret", 0); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Errorf("Search without user error = %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestIndexTTL(t *testing.T) {
+	ctx := userContext(t, "alice")
+	x := NewIndex()
+	x.TTL = time.Hour
+	buildIndex(t, ctx, x)
+	if !x.Built(ctx) {
+		t.Fatal("Built = false within TTL")
+	}
+	u, _ := x.user(ctx)
+	u.builtAt = time.Now().Add(-2 * time.Hour)
+	if x.Built(ctx) {
+		t.Error("Built = true after TTL")
+	}
+	x.TTL = 0
+	if !x.Built(ctx) {
+		t.Error("Built = false without TTL")
+	}
+}
+
+func TestIndexRebuildError(t *testing.T) {
+	ctx := userContext(t, "alice")
+	x := NewIndex()
+	buildIndex(t, ctx, x, newLeaf(t, leafSpec{id: "a", title: "terraform"}))
+	loadErr := errors.New("load failed")
+	if err := x.Rebuild(ctx, func() ([]*domain.Leaf, error) { return nil, loadErr }); !errors.Is(err, loadErr) {
+		t.Fatalf("Rebuild error = %v, want %v", err, loadErr)
+	}
+	// 読み込みに失敗したら作成済みの索引を残す
+	if got := hitIDs(t, ctx, x, "terraform", 0); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("after failed rebuild = %v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 1語として扱う最大文字数（長いIDやハッシュで索引が膨らまないよう切り詰める）
+const maxTokenRunes = 64
+
+// 文章を索引・検索の語に分割する
+// NFKCで全角・半角をそろえて小文字にし、英数字などは空白や記号で区切った単語、
+// 日本語（漢字・ひらがな・カタカナ）は分かち書きの代わりに隣り合う2文字ずつ（bigram）にする
+// 例: "DynamoDBの設計" → dynamodb, の設, 設計
+// 日本語1文字だけの語（"京"）はそのまま1語にする
+func tokenize(text string) []string {
+	text = strings.ToLower(norm.NFKC.String(text))
+	var tokens []string
+	var run []rune
+	cjk := false
+	flush := func() {
+		switch {
+		case len(run) == 0:
+		case cjk && len(run) > 1:
+			for i := 0; i+1 < len(run); i++ {
+				tokens = append(tokens, string(run[i:i+2]))
+			}
+		default:
+			tokens = append(tokens, string(run[:min(len(run), maxTokenRunes)]))
+		}
+		run = run[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			run = append(run, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// 分かち書きせずにbigramにする文字か（長音記号と繰り返し記号を含む）
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー' || r == '々'
+}
+
+// 日本語1文字の語か（bigramの前後どちらの文字にも一致させる）
+func isCJKUnigram(term string) bool {
+	r, size := utf8.DecodeRuneInString(term)
+	return size == len(term) && isCJK(r)
+}
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"  ,.!  ", nil},
+		{"DynamoDB Design", []string{"dynamodb", "design"}},
+		{"go-lang/aws_sdk v2.0", []string{"go", "lang", "aws", "sdk", "v2", "0"}},
+		// 全角英数字はNFKCで半角にそろえる
+		{"ＤｙｎａｍｏＤＢ　１２３", []string{"dynamodb", "123"}},
+		// 日本語は隣り合う2文字ずつにし、英数字との境目で区切る
+		{"DynamoDBの設計", []string{"dynamodb", "の設", "設計"}},
+		{"東京", []string{"東京"}},
+		{"京", []string{"京"}},
+		{"設計、実装", []string{"設計", "実装"}},
+		// 長音記号と繰り返し記号もbigramに含める
+		{"サーバー々", []string{"サー", "ーバ", "バー", "ー々"}},
+		// 半角カナも全角にそろえる
+		{"ｻｰﾊﾞｰ", []string{"サー", "ーバ", "バー"}},
+		{"Go言語2024年", []string{"go", "言語", "2024", "年"}},
+		{"café", []string{"café"}},
+		{"https://example.com/a?b=1", []string{"https", "example", "com", "a", "b", "1"}},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeTruncatesLongTokens(t *testing.T) {
+	got := tokenize(strings.Repeat("a", 100))
+	if len(got) != 1 || len(got[0]) != maxTokenRunes {
+		t.Errorf("tokenize(100 chars) = %q, want one token of %d chars", got, maxTokenRunes)
+	}
+}
+
+func TestIsCJKUnigram(t *testing.T) {
+	for term, want := range map[string]bool{"設": true, "ー": true, "a": false, "設計": false, "1": false, "": false} {
+		if got := isCJKUnigram(term); got != want {
+			t.Errorf("isCJKUnigram(%q) = %v, want %v", term, got, want)
+		}
+	}
+}
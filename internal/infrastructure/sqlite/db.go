@@ -0,0 +1,259 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	_ "modernc.org/sqlite"
+)
+
+// スキーママイグレーション
+// バージョン順に並べ、適用済みのものは変更しないこと
+// SQLだけで書けない移行はapplyで行う（stmtsの後に同じトランザクションで実行する）
+var migrations = []struct {
+	version int
+	stmts   []string
+	apply   func(ctx context.Context, tx *sql.Tx) error
+}{
+	{
+		version: 1,
+		stmts: []string{
+			`CREATE TABLE leaves (
+				id        TEXT PRIMARY KEY,
+				note      TEXT NOT NULL,
+				url       TEXT NOT NULL,
+				platform  TEXT NOT NULL,
+				read      INTEGER NOT NULL DEFAULT 0,
+				synced_at TEXT NOT NULL,
+				note_sort TEXT NOT NULL,
+				read_sort TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_leaves_platform ON leaves (platform)`,
+			`CREATE INDEX idx_leaves_synced_at ON leaves (synced_at, id)`,
+			`CREATE INDEX idx_leaves_note_sort ON leaves (note_sort, id)`,
+			`CREATE INDEX idx_leaves_read_sort ON leaves (read_sort, id)`,
+			`CREATE TABLE leaf_tags (
+				leaf_id  TEXT NOT NULL REFERENCES leaves (id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				tag      TEXT NOT NULL,
+				PRIMARY KEY (leaf_id, tag)
+			)`,
+			`CREATE INDEX idx_leaf_tags_tag ON leaf_tags (tag, leaf_id)`,
+		},
+	},
+	{
+		// 利用者ごとの分離（既存データは単一ユーザー運用時の利用者meのもの）
+		version: 2,
+		stmts: []string{
+			`ALTER TABLE leaves ADD COLUMN user_id TEXT NOT NULL DEFAULT 'me'`,
+			`DROP INDEX idx_leaves_platform`,
+			`DROP INDEX idx_leaves_synced_at`,
+			`DROP INDEX idx_leaves_note_sort`,
+			`DROP INDEX idx_leaves_read_sort`,
+			`CREATE INDEX idx_leaves_user_id ON leaves (user_id, id)`,
+			`CREATE INDEX idx_leaves_user_platform ON leaves (user_id, platform)`,
+			`CREATE INDEX idx_leaves_user_synced_at ON leaves (user_id, synced_at, id)`,
+			`CREATE INDEX idx_leaves_user_note_sort ON leaves (user_id, note_sort, id)`,
+			`CREATE INDEX idx_leaves_user_read_sort ON leaves (user_id, read_sort, id)`,
+		},
+	},
+	{
+		// 楽観ロック用のバージョン
+		version: 3,
+		stmts: []string{
+			`ALTER TABLE leaves ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		},
+	},
+	{
+		// 利用者ごとのURLの一意性（既存の重複は古いLeafを登録済みとして扱う）
+		version: 4,
+		stmts: []string{
+			`CREATE TABLE leaf_urls (
+				user_id TEXT NOT NULL,
+				url     TEXT NOT NULL,
+				leaf_id TEXT NOT NULL UNIQUE REFERENCES leaves (id) ON DELETE CASCADE,
+				PRIMARY KEY (user_id, url)
+			)`,
+			`INSERT OR IGNORE INTO leaf_urls (user_id, url, leaf_id)
+				SELECT user_id, url, id FROM leaves ORDER BY synced_at, id`,
+		},
+	},
+	{
+		// ゴミ箱（NULLならゴミ箱にない）
+		version: 5,
+		stmts: []string{
+			`ALTER TABLE leaves ADD COLUMN deleted_at TEXT`,
+			`CREATE INDEX leaves_user_deleted_at ON leaves (user_id, deleted_at)`,
+		},
+	},
+	{
+		// タグの別名
+		version: 6,
+		stmts: []string{
+			`CREATE TABLE tag_aliases (
+				user_id TEXT NOT NULL,
+				alias   TEXT NOT NULL,
+				tag     TEXT NOT NULL,
+				PRIMARY KEY (user_id, alias)
+			)`,
+		},
+	},
+	{
+		// 読書状態（既存データはreadから未読・読了に振り分ける）と遷移した日時
+		version: 7,
+		stmts: []string{
+			`ALTER TABLE leaves ADD COLUMN status TEXT NOT NULL DEFAULT 'unread'`,
+			`ALTER TABLE leaves ADD COLUMN started_at TEXT`,
+			`ALTER TABLE leaves ADD COLUMN read_at TEXT`,
+			`ALTER TABLE leaves ADD COLUMN archived_at TEXT`,
+			`UPDATE leaves SET status = 'read' WHERE read = 1`,
+			`CREATE INDEX idx_leaves_user_status ON leaves (user_id, status)`,
+		},
+	},
+	{
+		// 登録・変更日時と、同期日時順の並び替えキー（同期していなければ登録日時）
+		// 既存データは同期日時を登録・変更日時とし、同期（Qiita）以外で登録したLeafの同期日時は空にする
+		version: 8,
+		stmts: []string{
+			`ALTER TABLE leaves ADD COLUMN created_at TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE leaves ADD COLUMN updated_at TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE leaves ADD COLUMN synced_sort TEXT NOT NULL DEFAULT ''`,
+			`UPDATE leaves SET created_at = synced_at, updated_at = synced_at, synced_sort = synced_at`,
+			`UPDATE leaves SET synced_at = '' WHERE platform <> 'qiita'`,
+			`DROP INDEX idx_leaves_user_synced_at`,
+			`CREATE INDEX idx_leaves_user_synced_sort ON leaves (user_id, synced_sort, id)`,
+		},
+	},
+	{
+		// タイトル・説明と利用者のメモの分離
+		// 既存のnoteはタイトルとし、同期（Qiita）で登録したLeafのメモは空にする（記事のタイトルが入っていたため）
+		version: 9,
+		stmts: []string{
+			`ALTER TABLE leaves ADD COLUMN title TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE leaves ADD COLUMN description TEXT NOT NULL DEFAULT ''`,
+			`UPDATE leaves SET title = note`,
+			`UPDATE leaves SET note = '' WHERE platform = 'qiita'`,
+			`ALTER TABLE leaves RENAME COLUMN note_sort TO title_sort`,
+			`DROP INDEX idx_leaves_user_note_sort`,
+			`CREATE INDEX idx_leaves_user_title_sort ON leaves (user_id, title_sort, id)`,
+		},
+	},
+	{
+		// URLのページから取得した情報（サイト名・正規のURL・画像・公開日時と取得した日時）
+		version: 10,
+		stmts: []string{
+			`ALTER TABLE leaves ADD COLUMN site_name TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE leaves ADD COLUMN canonical_url TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE leaves ADD COLUMN image_url TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE leaves ADD COLUMN published_at TEXT`,
+			`ALTER TABLE leaves ADD COLUMN fetched_at TEXT`,
+		},
+	},
+	{
+		// URLの索引を正規化したURLで作り直す
+		// 正規化すると同じになるLeafは、先に登録したLeafを索引に載せる（残りのLeafはそのまま残る）
+		version: 11,
+		stmts: []string{
+			`DELETE FROM leaf_urls`,
+		},
+		apply: canonicalizeURLIndex,
+	},
+}
+
+func canonicalizeURLIndex(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT user_id, id, url FROM leaves ORDER BY created_at, id`)
+	if err != nil {
+		return err
+	}
+	type entry struct{ userID, id, url string }
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.userID, &e.id, &e.url); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO leaf_urls (user_id, url, leaf_id) VALUES (?, ?, ?)`,
+			e.userID, domain.CanonicalizeURL(e.url), e.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLITE_PATH（未設定ならlogleaf.db）のデータベースを開き、未適用のマイグレーションを適用する
+func NewSQLiteDB(ctx context.Context) (*sql.DB, error) {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = "logleaf.db"
+	}
+	return Open(ctx, path)
+}
+
+// pathのデータベースを開き、未適用のマイグレーションを適用する
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLiteの書き込みは直列なので接続を1本に絞り、ロック競合を避ける
+	db.SetMaxOpenConns(1)
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range m.stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("マイグレーション %d に失敗しました: %w", m.version, err)
+			}
+		}
+		if m.apply != nil {
+			if err := m.apply(ctx, tx); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("マイグレーション %d に失敗しました: %w", m.version, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, m.version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,645 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/storage"
+)
+
+type LeafSQLiteRepository struct {
+	DB *sql.DB
+	// ゴミ箱のLeafを保持する期間（0なら無期限）
+	// 期限を過ぎたLeafは次の書き込み時に削除する
+	TrashRetention time.Duration
+}
+
+func NewLeafSQLiteRepository(db *sql.DB) *LeafSQLiteRepository {
+	return &LeafSQLiteRepository{DB: db}
+}
+
+// 並び替え項目ごとのインデックス付き列
+var sortColumns = map[string]string{
+	domain.SortBySyncedAt: "synced_sort",
+	domain.SortByTitle:    "title_sort",
+	domain.SortByRead:     "read_sort",
+}
+
+const leafColumns = `id, note, url, platform, read, synced_at, title_sort, read_sort, deleted_at, version, status, started_at, read_at, archived_at, created_at, updated_at, synced_sort, title, description, site_name, canonical_url, image_url, published_at, fetched_at`
+
+func (r *LeafSQLiteRepository) Get(ctx context.Context, id string) (*domain.Leaf, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row := r.DB.QueryRowContext(ctx, `SELECT `+leafColumns+` FROM leaves WHERE user_id = ? AND id = ?`, userID.String(), id)
+	record, err := scanLeaf(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrLeafNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := r.toLeaves(ctx, []leafRow{record})
+	if err != nil {
+		return nil, err
+	}
+	return &leaves[0], nil
+}
+
+func (r *LeafSQLiteRepository) FindByURL(ctx context.Context, url string) (*domain.Leaf, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var id string
+	err = r.DB.QueryRowContext(ctx, `SELECT leaf_id FROM leaf_urls WHERE user_id = ? AND url = ?`, userID.String(), domain.CanonicalizeURL(url)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrLeafNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+// IN句に渡すURLの最大数
+const lookupChunkSize = 500
+
+func (r *LeafSQLiteRepository) LookupURLs(ctx context.Context, urls []string) (map[string]string, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 索引は正規化したURLで引き、渡されたURLに戻して返す
+	requested := make(map[string][]string, len(urls))
+	canonicals := make([]string, 0, len(urls))
+	for _, url := range urls {
+		canonical := domain.CanonicalizeURL(url)
+		if _, ok := requested[canonical]; !ok {
+			canonicals = append(canonicals, canonical)
+		}
+		requested[canonical] = append(requested[canonical], url)
+	}
+	found := make(map[string]string)
+	for chunk := range slices.Chunk(canonicals, lookupChunkSize) {
+		args := []any{userID.String()}
+		for _, url := range chunk {
+			args = append(args, url)
+		}
+		rows, err := r.DB.QueryContext(ctx,
+			`SELECT url, leaf_id FROM leaf_urls WHERE user_id = ? AND url IN (`+placeholders(len(chunk))+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var url, id string
+			if err := rows.Scan(&url, &id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			for _, requestedURL := range requested[url] {
+				found[requestedURL] = id
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+func (r *LeafSQLiteRepository) List(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, string, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	column, ok := sortColumns[opts.SortBy]
+	if opts.SortBy != "" && !ok {
+		return nil, "", domain.ErrUnsupportedSort
+	}
+	where := []string{"user_id = ?"}
+	args := []any{userID.String()}
+
+	// 絞り込み
+	if len(opts.Platforms) > 0 {
+		where = append(where, "platform IN ("+placeholders(len(opts.Platforms))+")")
+		for _, p := range opts.Platforms {
+			args = append(args, p)
+		}
+	}
+	if tags := slices.Compact(slices.Sorted(slices.Values(opts.Tags))); len(tags) > 0 {
+		if opts.TagMatch == domain.TagMatchAll {
+			for _, t := range tags {
+				cond, condArgs := tagCondition(t)
+				where = append(where, "id IN (SELECT leaf_id FROM leaf_tags WHERE "+cond+")")
+				args = append(args, condArgs...)
+			}
+		} else {
+			conds := make([]string, len(tags))
+			for i, t := range tags {
+				var condArgs []any
+				conds[i], condArgs = tagCondition(t)
+				args = append(args, condArgs...)
+			}
+			where = append(where, "id IN (SELECT leaf_id FROM leaf_tags WHERE "+strings.Join(conds, " OR ")+")")
+		}
+	}
+	if opts.Read != nil {
+		where = append(where, "read = ?")
+		args = append(args, *opts.Read)
+	}
+	if len(opts.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(opts.Statuses))+")")
+		for _, s := range opts.Statuses {
+			args = append(args, s.String())
+		}
+	}
+	if opts.Trashed {
+		where = append(where, "deleted_at IS NOT NULL")
+	} else {
+		where = append(where, "deleted_at IS NULL")
+	}
+
+	// カーソル位置より後ろだけを読む（キーセットページング）
+	op, dir := ">", "ASC"
+	if opts.SortDesc {
+		op, dir = "<", "DESC"
+	}
+	if opts.Cursor != "" {
+		c, err := storage.DecodeCursor(opts.Cursor, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		if column == "" {
+			where = append(where, "id "+op+" ?")
+			args = append(args, c.ID)
+		} else {
+			where = append(where, "("+column+", id) "+op+" (?, ?)")
+			args = append(args, c.Key, c.ID)
+		}
+	}
+
+	query := `SELECT ` + leafColumns + ` FROM leaves WHERE ` + strings.Join(where, " AND ")
+	if column == "" {
+		query += " ORDER BY id " + dir
+	} else {
+		query += " ORDER BY " + column + " " + dir + ", id " + dir
+	}
+	// 次ページの有無を判定するため1件多く読む
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit+1)
+	}
+
+	records, err := r.queryLeaves(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if opts.Limit > 0 && len(records) > opts.Limit {
+		records = records[:opts.Limit]
+		last := records[len(records)-1]
+		next = storage.EncodeCursor(storage.Cursor{SortBy: opts.SortBy, Desc: opts.SortDesc, Key: last.column(column), ID: last.ID})
+	}
+	leaves, err := r.toLeaves(ctx, records)
+	if err != nil {
+		return nil, "", err
+	}
+	return leaves, next, nil
+}
+
+func (r *LeafSQLiteRepository) Put(ctx context.Context, leaf *domain.Leaf) (*domain.Leaf, error) {
+	err := r.write(ctx, leaf, func(tx *sql.Tx, userID string) error {
+		return insert(ctx, tx, userID, leaf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+func (r *LeafSQLiteRepository) PutMany(ctx context.Context, leaves []*domain.Leaf) ([]*domain.Leaf, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := r.purgeTrash(ctx, tx, userID.String()); err != nil {
+		return nil, err
+	}
+	var stored []*domain.Leaf
+	for _, leaf := range leaves {
+		var dupErr *domain.DuplicateURLError
+		switch err := insert(ctx, tx, userID.String(), leaf); {
+		case errors.As(err, &dupErr), errors.Is(err, domain.ErrVersionConflict):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		if err := writeTags(ctx, tx, leaf); err != nil {
+			return nil, err
+		}
+		stored = append(stored, leaf)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, leaf := range stored {
+		leaf.IncrementVersion()
+	}
+	return stored, nil
+}
+
+// 新しいLeafを挿入する（タグは含まない）
+// URLが登録済みなら*DuplicateURLError、IDが登録済み（他の利用者のものを含む）ならErrVersionConflictで、何も書き込まない
+func insert(ctx context.Context, tx *sql.Tx, userID string, leaf *domain.Leaf) error {
+	var existing string
+	err := tx.QueryRowContext(ctx, `SELECT leaf_id FROM leaf_urls WHERE user_id = ? AND url = ?`, userID, leaf.URL().Canonical()).Scan(&existing)
+	if err == nil {
+		return &domain.DuplicateURLError{ExistingID: existing}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO leaves (user_id, id, note, url, platform, read, synced_at, title_sort, read_sort, deleted_at, version, status, started_at, read_at, archived_at, created_at, updated_at, synced_sort, title, description,
+			site_name, canonical_url, image_url, published_at, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		append([]any{userID, leaf.ID().String()}, leafValues(leaf)...)...,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrVersionConflict
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO leaf_urls (user_id, url, leaf_id) VALUES (?, ?, ?)`, userID, leaf.URL().Canonical(), leaf.ID().String())
+	return err
+}
+
+func (r *LeafSQLiteRepository) Update(ctx context.Context, update *domain.Leaf) error {
+	return r.write(ctx, update, func(tx *sql.Tx, userID string) error {
+		var storedURL string
+		var version int
+		err := tx.QueryRowContext(ctx, `SELECT url, version FROM leaves WHERE user_id = ? AND id = ?`, userID, update.ID().String()).Scan(&storedURL, &version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrLeafNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// 読み込んだ時点のバージョンのままである場合だけ書き込む
+		// （接続は1本なので、同じトランザクション内で読んだ値は書き込みまで変わらない）
+		if version != update.Version() {
+			return domain.ErrVersionConflict
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE leaves SET
+				note = ?, url = ?, platform = ?, read = ?, synced_at = ?, title_sort = ?, read_sort = ?, deleted_at = ?, version = ?,
+				status = ?, started_at = ?, read_at = ?, archived_at = ?, created_at = ?, updated_at = ?, synced_sort = ?, title = ?, description = ?,
+				site_name = ?, canonical_url = ?, image_url = ?, published_at = ?, fetched_at = ?
+			WHERE user_id = ? AND id = ?`,
+			append(leafValues(update), userID, update.ID().String())...,
+		)
+		if err != nil {
+			return err
+		}
+		if domain.CanonicalizeURL(storedURL) == update.URL().Canonical() {
+			return nil
+		}
+		return moveURL(ctx, tx, userID, update)
+	})
+}
+
+// 変更後のURLを索引に反映する（他のLeafと重複すれば*DuplicateURLError）
+func moveURL(ctx context.Context, tx *sql.Tx, userID string, leaf *domain.Leaf) error {
+	var existing string
+	err := tx.QueryRowContext(ctx, `SELECT leaf_id FROM leaf_urls WHERE user_id = ? AND url = ?`, userID, leaf.URL().Canonical()).Scan(&existing)
+	if err == nil {
+		if existing == leaf.ID().String() {
+			return nil
+		}
+		return &domain.DuplicateURLError{ExistingID: existing}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM leaf_urls WHERE leaf_id = ?`, leaf.ID().String()); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO leaf_urls (user_id, url, leaf_id) VALUES (?, ?, ?)`, userID, leaf.URL().Canonical(), leaf.ID().String())
+	return err
+}
+
+func (r *LeafSQLiteRepository) Delete(ctx context.Context, id string) error {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	// leaf_tags, leaf_urlsはON DELETE CASCADEで削除される
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM leaves WHERE user_id = ? AND id = ?`, userID.String(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrLeafNotFound
+	}
+	return nil
+}
+
+func (r *LeafSQLiteRepository) ListTags(ctx context.Context) ([]domain.TagCount, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT t.tag, COUNT(*), SUM(l.read = 0)
+		FROM leaf_tags t JOIN leaves l ON l.id = t.leaf_id
+		WHERE l.user_id = ? AND l.deleted_at IS NULL
+		GROUP BY t.tag
+		ORDER BY t.tag`, userID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := []domain.TagCount{}
+	for rows.Next() {
+		var c domain.TagCount
+		if err := rows.Scan(&c.Tag, &c.Leaves, &c.Unread); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// Leaf本体（stmt）とタグを1トランザクションで書き込む
+// 成功したらLeafのバージョンを進める
+func (r *LeafSQLiteRepository) write(ctx context.Context, leaf *domain.Leaf, stmt func(tx *sql.Tx, userID string) error) error {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := r.purgeTrash(ctx, tx, userID.String()); err != nil {
+		return err
+	}
+	if err := stmt(tx, userID.String()); err != nil {
+		return err
+	}
+	if err := writeTags(ctx, tx, leaf); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	leaf.IncrementVersion()
+	return nil
+}
+
+// 保持期間を過ぎたゴミ箱のLeafを削除する（leaf_tags, leaf_urlsはON DELETE CASCADEで削除される）
+func (r *LeafSQLiteRepository) purgeTrash(ctx context.Context, tx *sql.Tx, userID string) error {
+	if r.TrashRetention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-r.TrashRetention).UTC().Format(time.RFC3339)
+	_, err := tx.ExecContext(ctx, `DELETE FROM leaves WHERE user_id = ? AND deleted_at <= ?`, userID, cutoff)
+	return err
+}
+
+// Leafのタグを書き直す
+// 絞り込みのタグの条件に一致するleaf_tagsの条件
+// 子孫のパターン（aws/*）は「aws/」で始まるタグの範囲として索引で引く（「0」は「/」の次の文字）
+func tagCondition(pattern string) (string, []any) {
+	if parent, ok := domain.TagPatternParent(pattern); ok {
+		return "(tag >= ? AND tag < ?)", []any{parent + domain.TagSeparator, parent + "0"}
+	}
+	return "tag = ?", []any{pattern}
+}
+
+func writeTags(ctx context.Context, tx *sql.Tx, leaf *domain.Leaf) error {
+	id := leaf.ID().String()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM leaf_tags WHERE leaf_id = ?`, id); err != nil {
+		return err
+	}
+	for i, t := range leaf.Tags() {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO leaf_tags (leaf_id, position, tag) VALUES (?, ?, ?)`, id, i, t.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// note〜fetched_atの列に書き込む値（versionは保存後のバージョン）
+// 日時はRFC3339（UTC）で保存し、文字列の比較で前後を判定できるようにする
+// readは読書状態から導出し、既読の絞り込みと並び替えに使う
+func leafValues(leaf *domain.Leaf) []any {
+	reading := leaf.ReadingState()
+	page := leaf.Page()
+	return []any{
+		leaf.Note(),
+		leaf.URL().String(),
+		leaf.Platform(),
+		leaf.Read(),
+		storage.FormatTime(leaf.SyncedAt()),
+		domain.LeafSortKey(leaf, domain.SortByTitle),
+		domain.LeafSortKey(leaf, domain.SortByRead),
+		nullTime(leaf.DeletedAt()),
+		leaf.Version() + 1,
+		reading.Status.String(),
+		nullTime(reading.StartedAt),
+		nullTime(reading.ReadAt),
+		nullTime(reading.ArchivedAt),
+		storage.FormatTime(leaf.CreatedAt()),
+		storage.FormatTime(leaf.UpdatedAt()),
+		domain.LeafSortKey(leaf, domain.SortBySyncedAt),
+		leaf.Title(),
+		leaf.Description(),
+		page.SiteName,
+		page.CanonicalURL,
+		page.ImageURL,
+		nullTime(page.PublishedAt),
+		nullTime(page.FetchedAt),
+	}
+}
+
+// 日時の列の値（ゼロ値ならNULL）
+func nullTime(t time.Time) sql.NullString {
+	return sql.NullString{String: t.UTC().Format(time.RFC3339), Valid: !t.IsZero()}
+}
+
+func parseNullTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s.String)
+}
+
+// leavesテーブルの1行
+type leafRow struct {
+	ID        string
+	Note      string
+	URL       string
+	Platform  string
+	Read      bool
+	SyncedAt  string
+	TitleSort string
+	ReadSort  string
+	DeletedAt sql.NullString
+	Version   int
+	// 読書状態と遷移した日時
+	Status      string
+	StartedAt   sql.NullString
+	ReadAt      sql.NullString
+	ArchivedAt  sql.NullString
+	CreatedAt   string
+	UpdatedAt   string
+	SyncedSort  string
+	Title       string
+	Description string
+	// URLのページから取得した情報
+	SiteName     string
+	CanonicalURL string
+	ImageURL     string
+	PublishedAt  sql.NullString
+	FetchedAt    sql.NullString
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLeaf(s scanner) (leafRow, error) {
+	var row leafRow
+	err := s.Scan(&row.ID, &row.Note, &row.URL, &row.Platform, &row.Read, &row.SyncedAt, &row.TitleSort, &row.ReadSort, &row.DeletedAt, &row.Version,
+		&row.Status, &row.StartedAt, &row.ReadAt, &row.ArchivedAt, &row.CreatedAt, &row.UpdatedAt, &row.SyncedSort,
+		&row.Title, &row.Description, &row.SiteName, &row.CanonicalURL, &row.ImageURL, &row.PublishedAt, &row.FetchedAt)
+	return row, err
+}
+
+func (r *LeafSQLiteRepository) queryLeaves(ctx context.Context, query string, args ...any) ([]leafRow, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []leafRow
+	for rows.Next() {
+		record, err := scanLeaf(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// 行にタグを付けてEntityに変換
+func (r *LeafSQLiteRepository) toLeaves(ctx context.Context, records []leafRow) ([]domain.Leaf, error) {
+	if len(records) == 0 {
+		return []domain.Leaf{}, nil
+	}
+	ids := make([]any, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT leaf_id, tag FROM leaf_tags WHERE leaf_id IN (`+placeholders(len(ids))+`) ORDER BY leaf_id, position`, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var leafID, tag string
+		if err := rows.Scan(&leafID, &tag); err != nil {
+			return nil, err
+		}
+		tags[leafID] = append(tags[leafID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	leaves := make([]domain.Leaf, len(records))
+	for i, record := range records {
+		createdAt, err := storage.ParseTime(record.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		updatedAt, err := storage.ParseTime(record.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		syncedAt, err := storage.ParseTime(record.SyncedAt)
+		if err != nil {
+			return nil, err
+		}
+		deletedAt, err := parseNullTime(record.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		reading := domain.ReadingState{Status: domain.LeafStatus(record.Status)}
+		if reading.StartedAt, err = parseNullTime(record.StartedAt); err != nil {
+			return nil, err
+		}
+		if reading.ReadAt, err = parseNullTime(record.ReadAt); err != nil {
+			return nil, err
+		}
+		if reading.ArchivedAt, err = parseNullTime(record.ArchivedAt); err != nil {
+			return nil, err
+		}
+		page := domain.PageInfo{SiteName: record.SiteName, CanonicalURL: record.CanonicalURL, ImageURL: record.ImageURL}
+		if page.PublishedAt, err = parseNullTime(record.PublishedAt); err != nil {
+			return nil, err
+		}
+		if page.FetchedAt, err = parseNullTime(record.FetchedAt); err != nil {
+			return nil, err
+		}
+		leaf, err := domain.ReconstructLeaf(record.ID, record.Title, record.Description, record.Note, record.URL, record.Platform, tags[record.ID], reading, page, createdAt, updatedAt, syncedAt, deletedAt, record.Version)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = *leaf
+	}
+	return leaves, nil
+}
+
+// 並び替え列の値（カーソル用）
+func (row leafRow) column(name string) string {
+	switch name {
+	case "synced_sort":
+		return row.SyncedSort
+	case "title_sort":
+		return row.TitleSort
+	case "read_sort":
+		return row.ReadSort
+	}
+	return ""
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
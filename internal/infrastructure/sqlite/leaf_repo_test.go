@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/domain/repotest"
+)
+
+// テストごとに一時ディレクトリに作成した空のDB
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "logleaf.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLeafRepository(t *testing.T) {
+	repotest.TestLeafRepository(t, func(t *testing.T) domain.LeafRepository {
+		return NewLeafSQLiteRepository(openTestDB(t))
+	})
+}
+
+func TestTagAliasRepository(t *testing.T) {
+	repotest.TestTagAliasRepository(t, func(t *testing.T) domain.TagAliasRepository {
+		return NewTagAliasSQLiteRepository(openTestDB(t))
+	})
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+type TagAliasSQLiteRepository struct {
+	DB *sql.DB
+}
+
+func NewTagAliasSQLiteRepository(db *sql.DB) *TagAliasSQLiteRepository {
+	return &TagAliasSQLiteRepository{DB: db}
+}
+
+func (r *TagAliasSQLiteRepository) ListAliases(ctx context.Context) ([]domain.TagAlias, error) {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.QueryContext(ctx, `SELECT alias, tag FROM tag_aliases WHERE user_id = ? ORDER BY alias`, userID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	aliases := []domain.TagAlias{}
+	for rows.Next() {
+		var alias, tag string
+		if err := rows.Scan(&alias, &tag); err != nil {
+			return nil, err
+		}
+		a, err := domain.NewTagAlias(alias, tag)
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+func (r *TagAliasSQLiteRepository) PutAlias(ctx context.Context, alias domain.TagAlias) error {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.ExecContext(ctx, `
+		INSERT INTO tag_aliases (user_id, alias, tag) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, alias) DO UPDATE SET tag = excluded.tag`,
+		userID.String(), alias.Alias().String(), alias.Tag().String())
+	return err
+}
+
+func (r *TagAliasSQLiteRepository) DeleteAlias(ctx context.Context, alias string) error {
+	userID, err := domain.UserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM tag_aliases WHERE user_id = ? AND alias = ?`, userID.String(), alias)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrTagAliasNotFound
+	}
+	return nil
+}
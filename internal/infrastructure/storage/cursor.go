@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// 最後に返したLeafの位置（並び替えキーとID）を表すカーソル
+// 並び替え条件が異なるリクエストでは使えない
+type Cursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Key    string `json:"k"`
+	ID     string `json:"i"`
+}
+
+// クライアントに渡す不透明なカーソル文字列に変換する
+func EncodeCursor(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// カーソル文字列を戻す（optsと並び替え条件が異なればErrInvalidCursor）
+func DecodeCursor(s string, opts domain.ListOptions) (Cursor, error) {
+	var c Cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, domain.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, domain.ErrInvalidCursor
+	}
+	if c.SortBy != opts.SortBy || c.Desc != opts.SortDesc || c.ID == "" {
+		return c, domain.ErrInvalidCursor
+	}
+	return c, nil
+}
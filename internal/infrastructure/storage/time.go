@@ -0,0 +1,19 @@
+package storage
+
+import "time"
+
+// 保存する日時の文字列（UTCのRFC 3339。ゼロ値なら空文字）
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// FormatTimeの文字列を戻す（空文字ならゼロ値）
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
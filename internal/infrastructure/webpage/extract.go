@@ -0,0 +1,211 @@
+package webpage
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLから集めたメタデータの候補
+type document struct {
+	title      string            // <title>
+	canonical  string            // <link rel="canonical">
+	meta       map[string]string // <meta property|name|itemprop>（同じ名前は最初の値）
+	linkedData []map[string]any  // JSON-LDのオブジェクト（記事を表すものを先に並べる）
+}
+
+// HTMLからメタデータを取り出す
+// 項目ごとにOpenGraph、JSON-LD、HTMLのmetaやtitleの順で最初に見つかった値を使う
+// URLはbaseを基準に絶対URLにし、http・https以外は捨てる
+func Extract(r io.Reader, base *url.URL) (*domain.PageMetadata, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := &document{meta: make(map[string]string)}
+	doc.collect(root)
+
+	return &domain.PageMetadata{
+		Title:        first(doc.meta["og:title"], doc.ld("headline"), doc.ld("name"), doc.meta["twitter:title"], doc.title),
+		Description:  first(doc.meta["og:description"], doc.ld("description"), doc.meta["description"], doc.meta["twitter:description"]),
+		SiteName:     first(doc.meta["og:site_name"], doc.ldPublisher(), doc.meta["application-name"]),
+		CanonicalURL: resolve(base, first(doc.canonical, doc.meta["og:url"], doc.ldURL("url"))),
+		ImageURL:     resolve(base, first(doc.meta["og:image"], doc.meta["og:image:url"], doc.ldURL("image"), doc.meta["twitter:image"])),
+		PublishedAt:  parseDate(first(doc.meta["article:published_time"], doc.ld("datePublished"), doc.meta["datepublished"])),
+	}, nil
+}
+
+func (d *document) collect(n *html.Node) {
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.Title:
+			if d.title == "" && n.FirstChild != nil {
+				d.title = clean(n.FirstChild.Data)
+			}
+		case atom.Meta:
+			key := first(attr(n, "property"), attr(n, "name"), attr(n, "itemprop"))
+			key = strings.ToLower(key)
+			if _, exists := d.meta[key]; key != "" && !exists {
+				if content := clean(attr(n, "content")); content != "" {
+					d.meta[key] = content
+				}
+			}
+		case atom.Link:
+			if d.canonical == "" && strings.EqualFold(attr(n, "rel"), "canonical") {
+				d.canonical = strings.TrimSpace(attr(n, "href"))
+			}
+		case atom.Script:
+			if strings.EqualFold(strings.TrimSpace(attr(n, "type")), "application/ld+json") && n.FirstChild != nil {
+				d.addLinkedData(n.FirstChild.Data)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		d.collect(c)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// JSON-LDを読み込む（配列と@graphは展開する。解析できなければ無視する）
+func (d *document) addLinkedData(data string) {
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return
+	}
+	var objects []map[string]any
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			if graph, ok := v["@graph"]; ok {
+				walk(graph)
+				return
+			}
+			objects = append(objects, v)
+		}
+	}
+	walk(v)
+	for _, obj := range objects {
+		if _, ok := obj["headline"]; ok {
+			d.linkedData = append([]map[string]any{obj}, d.linkedData...)
+		} else {
+			d.linkedData = append(d.linkedData, obj)
+		}
+	}
+}
+
+// JSON-LDの文字列の値
+func (d *document) ld(key string) string {
+	for _, obj := range d.linkedData {
+		if s, ok := obj[key].(string); ok && clean(s) != "" {
+			return clean(s)
+		}
+	}
+	return ""
+}
+
+// JSON-LDのURLの値（文字列、{"url": ...}、それらの配列のいずれか）
+func (d *document) ldURL(key string) string {
+	for _, obj := range d.linkedData {
+		if s := linkedURL(obj[key]); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func linkedURL(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]any:
+		return linkedURL(v["url"])
+	case []any:
+		for _, item := range v {
+			if s := linkedURL(item); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// JSON-LDの発行元（publisher.name）
+func (d *document) ldPublisher() string {
+	for _, obj := range d.linkedData {
+		if publisher, ok := obj["publisher"].(map[string]any); ok {
+			if name, ok := publisher["name"].(string); ok && clean(name) != "" {
+				return clean(name)
+			}
+		}
+	}
+	return ""
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// 連続する空白と改行を1つの空白にまとめる
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// 公開日時として使われる書式
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// 公開日時を解析する（解析できなければゼロ値）
+func parseDate(s string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,113 @@
+package webpage
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+func TestExtract(t *testing.T) {
+	base, _ := url.Parse("https://example.com/articles/1")
+	tests := []struct {
+		name string
+		html string
+		want domain.PageMetadata
+	}{
+		{
+			name: "OpenGraph",
+			html: `<html><head>
+				<title>HTMLのタイトル</title>
+				<meta property="og:title" content="OGのタイトル">
+				<meta property="og:description" content="  OGの
+					説明  ">
+				<meta property="og:site_name" content="Example">
+				<meta property="og:url" content="https://example.com/articles/1?ref=og">
+				<meta property="og:image" content="/images/1.png">
+				<meta property="article:published_time" content="2024-05-01T09:00:00+09:00">
+				<meta name="description" content="metaの説明">
+			</head></html>`,
+			want: domain.PageMetadata{
+				Title:        "OGのタイトル",
+				Description:  "OGの 説明",
+				SiteName:     "Example",
+				CanonicalURL: "https://example.com/articles/1?ref=og",
+				ImageURL:     "https://example.com/images/1.png",
+				PublishedAt:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "JSON-LD",
+			html: `<html><head>
+				<title>HTMLのタイトル</title>
+				<script type="application/ld+json">
+				{"@context": "https://schema.org", "@graph": [
+					{"@type": "WebSite", "name": "サイト名", "url": "https://example.com/"},
+					{"@type": "Article", "headline": "記事の見出し", "description": "記事の説明",
+					 "url": "https://example.com/articles/1", "image": [{"url": "https://cdn.example.com/1.jpg"}],
+					 "datePublished": "2024-05-01", "publisher": {"name": "発行元"}}
+				]}
+				</script>
+			</head></html>`,
+			want: domain.PageMetadata{
+				Title:        "記事の見出し",
+				Description:  "記事の説明",
+				SiteName:     "発行元",
+				CanonicalURL: "https://example.com/articles/1",
+				ImageURL:     "https://cdn.example.com/1.jpg",
+				PublishedAt:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "HTMLのtitleとmetaで補う",
+			html: `<html><head>
+				<title>
+					HTMLの  タイトル
+				</title>
+				<meta name="description" content="metaの説明">
+				<meta name="application-name" content="アプリ">
+				<link rel="canonical" href="../articles/1">
+				<meta name="twitter:image" content="https://example.com/twitter.png">
+			</head></html>`,
+			want: domain.PageMetadata{
+				Title:        "HTMLの タイトル",
+				Description:  "metaの説明",
+				SiteName:     "アプリ",
+				CanonicalURL: "https://example.com/articles/1",
+				ImageURL:     "https://example.com/twitter.png",
+			},
+		},
+		{
+			name: "壊れたJSON-LDとhttp以外のURLは無視する",
+			html: `<html><head>
+				<title>タイトル</title>
+				<script type="application/ld+json">{"headline": </script>
+				<meta property="og:image" content="javascript:alert(1)">
+				<link rel="canonical" href="data:text/html,x">
+			</head></html>`,
+			want: domain.PageMetadata{Title: "タイトル"},
+		},
+		{
+			name: "メタデータがない",
+			html: `<html><body><p>本文</p></body></html>`,
+			want: domain.PageMetadata{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(strings.NewReader(tt.html), base)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if !got.PublishedAt.Equal(tt.want.PublishedAt) {
+				t.Errorf("PublishedAt = %v, want %v", got.PublishedAt, tt.want.PublishedAt)
+			}
+			got.PublishedAt, tt.want.PublishedAt = time.Time{}, time.Time{}
+			if *got != tt.want {
+				t.Errorf("Extract =\n%+v\nwant\n%+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package webpage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"golang.org/x/net/html/charset"
+)
+
+// 読み込むHTMLの最大バイト数（メタデータはheadにあるため、先頭だけで足りる）
+const maxBodyBytes = 1 << 20
+
+const userAgent = "logleaf/1.0 (+https://github.com/umekikazuya/logleaf)"
+
+var ErrNotHTML = errors.New("HTML以外のページからはメタデータを取得できません。")
+
+// WebページのHTMLを取得し、OpenGraph・JSON-LD・HTMLのmetaからメタデータを取り出す
+// 利用者が登録したURLに接続するため、clientにはnetguardで接続先を制限したクライアントを渡すこと
+type Fetcher struct {
+	client *http.Client
+}
+
+func NewFetcher(client *http.Client) *Fetcher {
+	return &Fetcher{client: client}
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, url string) (*domain.PageMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// ステータスコードのチェック(200-299の範囲外はエラーとする)
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return nil, fmt.Errorf("ページの取得に失敗しました: %s", resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+			return nil, ErrNotHTML
+		}
+	}
+	// Content-Typeやmetaで指定された文字コードからUTF-8に変換する
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBodyBytes), contentType)
+	if err != nil {
+		return nil, err
+	}
+	// 相対URLはリダイレクト後のURLを基準に解決する
+	return Extract(body, resp.Request.URL)
+}
@@ -0,0 +1,114 @@
+package webpage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestFetcher(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().String(`<html><head><title>日本語のタイトル</title></head></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eucjp, err := japanese.EUCJP.NewEncoder().String(`<html><head><meta charset="euc-jp"><title>EUC-JPのタイトル</title></head></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><meta property="og:title" content="OGのタイトル"><meta property="og:image" content="/og.png"></head></html>`))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved/page", http.StatusFound)
+	})
+	mux.HandleFunc("/moved/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>移動先</title><link rel="canonical" href="canonical"></head></html>`))
+	})
+	mux.HandleFunc("/sjis", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+		w.Write([]byte(sjis))
+	})
+	mux.HandleFunc("/eucjp", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(eucjp))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"title": "JSON"}`))
+	})
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "error", http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	f := NewFetcher(srv.Client())
+	ctx := context.Background()
+
+	t.Run("OpenGraph", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/og")
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		if meta.Title != "OGのタイトル" || meta.ImageURL != srv.URL+"/og.png" {
+			t.Errorf("Fetch = %+v", meta)
+		}
+	})
+
+	t.Run("リダイレクト後のURLを基準に解決する", func(t *testing.T) {
+		meta, err := f.Fetch(ctx, srv.URL+"/redirect")
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		if meta.Title != "移動先" || meta.CanonicalURL != srv.URL+"/moved/canonical" {
+			t.Errorf("Fetch = %+v", meta)
+		}
+	})
+
+	t.Run("文字コードを変換する", func(t *testing.T) {
+		for path, want := range map[string]string{"/sjis": "日本語のタイトル", "/eucjp": "EUC-JPのタイトル"} {
+			meta, err := f.Fetch(ctx, srv.URL+path)
+			if err != nil {
+				t.Fatalf("Fetch(%s): %v", path, err)
+			}
+			if meta.Title != want {
+				t.Errorf("Fetch(%s).Title = %q, want %q", path, meta.Title, want)
+			}
+		}
+	})
+
+	t.Run("HTML以外はErrNotHTML", func(t *testing.T) {
+		for _, path := range []string{"/json", "/pdf"} {
+			if _, err := f.Fetch(ctx, srv.URL+path); !errors.Is(err, ErrNotHTML) {
+				t.Errorf("Fetch(%s) error = %v, want ErrNotHTML", path, err)
+			}
+		}
+	})
+
+	t.Run("2xx以外はエラー", func(t *testing.T) {
+		for path, status := range map[string]string{"/missing": "404", "/error": "500"} {
+			_, err := f.Fetch(ctx, srv.URL+path)
+			if err == nil || !strings.Contains(err.Error(), status) {
+				t.Errorf("Fetch(%s) error = %v, want status %s", path, err, status)
+			}
+		}
+	})
+}
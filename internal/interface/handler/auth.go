@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// Authorization: Bearer <トークン> から利用者を特定し、リクエストのコンテキストに設定する
+// tokensはAPIトークン → 所有者
+func Authenticate(tokens map[string]domain.UserID) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			unauthorized(c)
+			return
+		}
+		// トークン比較は定数時間で行う
+		var userID domain.UserID
+		found := false
+		for candidate, owner := range tokens {
+			if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+				userID, found = owner, true
+			}
+		}
+		if !found {
+			unauthorized(c)
+			return
+		}
+		c.Request = c.Request.WithContext(domain.WithUserID(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+// すべてのリクエストをuserIDの利用者として扱う
+// APIトークンを設定していない単一ユーザー運用で使う
+func SingleUser(userID domain.UserID) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(domain.WithUserID(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Bearer realm="logleaf"`)
+	writeProblem(c, problem{Status: http.StatusUnauthorized, Code: "unauthenticated"})
+}
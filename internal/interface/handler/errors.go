@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// エラーの種類に対応するHTTPステータスとエラーコード（上から順に判定する）
+// エラー自身がコードを持たない場合に、この表のコードを使う
+var errorStatuses = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{domain.ErrUnauthenticated, http.StatusUnauthorized, "unauthenticated"},
+	{domain.ErrPrecondition, http.StatusPreconditionFailed, "precondition_failed"},
+	{domain.ErrNotFound, http.StatusNotFound, "not_found"},
+	{domain.ErrValidation, http.StatusBadRequest, "validation_failed"},
+	{domain.ErrConflict, http.StatusConflict, "conflict"},
+}
+
+// RFC 7807のエラーレスポンス（application/problem+json）
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// 機械判定用のエラーコード（例: validation_failed, duplicate_url）
+	Code string `json:"code"`
+	// 項目ごとの検証エラー（検証エラーのときだけ）
+	Errors []fieldProblem `json:"errors,omitempty"`
+	// 同じURLで登録済みのLeafのID（URL重複のときだけ）
+	ExistingID string `json:"existing_id,omitempty"`
+	// 登録済みのLeafがゴミ箱にあるか（URL重複のときだけ）
+	ExistingTrashed bool `json:"existing_trashed,omitempty"`
+	// ゴミ箱のLeafを復元するときにPOSTするパス（ゴミ箱のLeafとURLが重複したときだけ）
+	Restore string `json:"restore,omitempty"`
+}
+
+type fieldProblem struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// エラーコードを持つエラー
+type codedError interface {
+	ErrorCode() string
+}
+
+// エラーをHTTPレスポンスとして返す
+// ドメインエラー以外（DB障害など）は詳細を返さず500にし、ログに残す
+func respondError(c *gin.Context, err error) {
+	p := problem{Status: http.StatusInternalServerError, Code: "internal"}
+	for _, s := range errorStatuses {
+		if errors.Is(err, s.kind) {
+			p.Status, p.Code = s.status, s.code
+			break
+		}
+	}
+	if p.Status == http.StatusInternalServerError {
+		_ = c.Error(err)
+		writeProblem(c, p)
+		return
+	}
+
+	p.Detail = err.Error()
+	var coded codedError
+	if errors.As(err, &coded) {
+		p.Code = coded.ErrorCode()
+	}
+	p.Errors = fieldProblems(err)
+	var dupErr *domain.DuplicateURLError
+	if errors.As(err, &dupErr) {
+		p.ExistingID = dupErr.ExistingID
+		if dupErr.Trashed {
+			p.ExistingTrashed = true
+			p.Restore = "/api/leaves/" + dupErr.ExistingID + "/restore"
+		}
+	}
+	writeProblem(c, p)
+}
+
+func writeProblem(c *gin.Context, p problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	p.Instance = c.Request.URL.Path
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// 検証エラーを項目ごとに展開する（検証エラーでなければnil）
+func fieldProblems(err error) []fieldProblem {
+	var many domain.ValidationErrors
+	if !errors.As(err, &many) {
+		var one *domain.ValidationError
+		if !errors.As(err, &one) {
+			return nil
+		}
+		many = domain.ValidationErrors{one}
+	}
+	problems := make([]fieldProblem, len(many))
+	for i, v := range many {
+		problems[i] = fieldProblem{Field: v.Field, Code: v.Code, Message: v.Message}
+	}
+	return problems
+}
+
+// リクエストの形式が不正（JSONやクエリパラメータを解釈できない）
+func invalidRequest(message string) error {
+	return &domain.ValidationError{Code: domain.CodeInvalidFormat, Message: message}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/umekikazuya/logleaf/internal/application"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// LeafのバージョンをETag（強いETag）として返す
+func setETag(c *gin.Context, leaf *domain.Leaf) {
+	c.Header("ETag", `"`+strconv.Itoa(leaf.Version())+`"`)
+}
+
+// If-Matchヘッダーからクライアントが前提とするバージョンを取り出す
+// 未指定や*はAnyVersion（条件なし）、解釈できない値はErrPreconditionFailed
+func ifMatchVersion(c *gin.Context) (int, error) {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" || raw == "*" {
+		return application.AnyVersion, nil
+	}
+	tag, ok := strings.CutPrefix(raw, `"`)
+	if !ok {
+		return 0, domain.ErrPreconditionFailed
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return 0, domain.ErrPreconditionFailed
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version < 0 {
+		return 0, domain.ErrPreconditionFailed
+	}
+	return version, nil
+}
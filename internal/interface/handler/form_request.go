@@ -1,15 +1,71 @@
 package handler
 
+// 必須項目はドメインで検証する（他の項目の不正とまとめて報告するため）
 type CreateLeafRequest struct {
-	Note     string   `json:"note" binding:"required"`
-	URL      string   `json:"url" binding:"required"`
-	Platform string   `json:"platform"`
-	Tags     []string `json:"tags"`
+	Title       string   `json:"title"` // 省略するとページのメタデータから補う
+	Description string   `json:"description"`
+	Note        string   `json:"note"` // 利用者自身のメモ（Markdown）
+	URL         string   `json:"url"`
+	Platform    string   `json:"platform"` // 省略するとURLのホスト名から判定する
+	Tags        []string `json:"tags"`
 }
 
+// URLは変更できない（送られても無視する）
 type UpdateLeafRequest struct {
-	Note     string   `json:"note" binding:"required"`
-	URL      string   `json:"url" binding:"required"`
-	Platform string   `json:"platform"`
-	Tags     []string `json:"tags"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"` // 省略すると変更しない（""で消す）
+	Note        *string   `json:"note"`        // 省略すると変更しない（""で消す）
+	Platform    string    `json:"platform"`
+	Tags        *[]string `json:"tags"` // 省略すると変更しない（[]で消す）
+}
+
+// GET /api/leaves のクエリパラメータ
+// 例: ?platform=qiita&tag=go&tag=aws&tag_match=all&read=false&status=reading&sort=-synced_at&limit=50&cursor=...
+type ListLeavesRequest struct {
+	Platforms []string `form:"platform"`
+	Tags      []string `form:"tag"`
+	TagMatch  string   `form:"tag_match" binding:"omitempty,oneof=any all"`
+	Read      *bool    `form:"read"`
+	Statuses  []string `form:"status"`                                                                                 // いずれかの読書状態に一致
+	Sort      string   `form:"sort" binding:"omitempty,oneof=synced_at -synced_at title -title note -note read -read"` // 先頭の-で降順（noteはtitleの旧名）
+	Limit     int      `form:"limit" binding:"omitempty,min=1,max=1000"`
+	Cursor    string   `form:"cursor"`
+}
+
+// GET /api/search のクエリパラメータ
+// 例: ?q=DynamoDB 設計&limit=20
+type SearchRequest struct {
+	Query string `form:"q"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// PATCH /api/leaves/:id/status
+type ChangeStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// POST /api/tags/:name/rename
+type RenameTagRequest struct {
+	To string `json:"to"`
+}
+
+// POST /api/tags/merge
+type MergeTagsRequest struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
+
+// POST /api/tags/:name/move
+type MoveTagRequest struct {
+	Parent string `json:"parent"` // 空なら最上位へ
+}
+
+// タグの一括付け替えのクエリパラメータ（1回に書き換えるLeafの数）
+type TagOperationQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// PUT /api/tag-aliases/:alias
+type PutTagAliasRequest struct {
+	Tag string `json:"tag"`
 }
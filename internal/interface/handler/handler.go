@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/umekikazuya/logleaf/internal/application"
@@ -18,13 +20,56 @@ func NewLeafHandler(u *application.LeafUsecase) *LeafHandler {
 
 // Index /api/leaves
 func (h *LeafHandler) ListLeaves(c *gin.Context) {
+	h.listLeaves(c, h.Usecase.ListLeaves)
+}
+
+// GET /api/trash
+func (h *LeafHandler) ListTrash(c *gin.Context) {
+	h.listLeaves(c, h.Usecase.ListTrash)
+}
+
+// 一覧系エンドポイント共通の処理（クエリパラメータの解釈とページング）
+func (h *LeafHandler) listLeaves(c *gin.Context, list func(context.Context, domain.ListOptions) ([]domain.Leaf, string, error)) {
 	// Parse query parameters for filtering options
+	var req ListLeavesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, bindError(err, "invalid query parameters"))
+		return
+	}
+	statuses := make([]domain.LeafStatus, len(req.Statuses))
+	for i, value := range req.Statuses {
+		status, err := domain.ParseLeafStatus(value)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		statuses[i] = status
+	}
 	opts := domain.ListOptions{
-		Limit: 100, // default limit
+		Platforms: req.Platforms,
+		Tags:      req.Tags,
+		TagMatch:  domain.TagMatch(req.TagMatch),
+		Read:      req.Read,
+		Statuses:  statuses,
+		Limit:     100, // default limit
+		Cursor:    req.Cursor,
+		SortBy:    domain.SortBySyncedAt, // default: newest first
+		SortDesc:  true,
+	}
+	if req.Sort != "" {
+		opts.SortBy = strings.TrimPrefix(req.Sort, "-")
+		opts.SortDesc = strings.HasPrefix(req.Sort, "-")
+		// 旧名（タイトルがnoteだった頃の指定）
+		if opts.SortBy == "note" {
+			opts.SortBy = domain.SortByTitle
+		}
 	}
-	leaves, err := h.Usecase.ListLeaves(c.Request.Context(), opts)
+	if req.Limit > 0 {
+		opts.Limit = req.Limit
+	}
+	leaves, nextCursor, err := list(c.Request.Context(), opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch leaves"})
+		respondError(c, err)
 		return
 	}
 	// Convert to output DTOs
@@ -32,20 +77,20 @@ func (h *LeafHandler) ListLeaves(c *gin.Context) {
 	for i, leaf := range leaves {
 		outputDTOs[i] = application.LeafDomainToOutputDTO(&leaf)
 	}
-	c.JSON(http.StatusOK, outputDTOs)
+	c.JSON(http.StatusOK, gin.H{
+		"items":       outputDTOs,
+		"next_cursor": nextCursor,
+	})
 }
 
 // GET /api/leaves/:id
 func (h *LeafHandler) GetLeaf(c *gin.Context) {
 	leaf, err := h.Usecase.GetLeaf(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if leaf == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "leaf not found"})
+		respondError(c, err)
 		return
 	}
+	setETag(c, leaf)
 	c.JSON(http.StatusOK, application.LeafDomainToOutputDTO(leaf))
 }
 
@@ -54,71 +99,151 @@ func (h *LeafHandler) AddLeaf(c *gin.Context) {
 	// Request
 	var req CreateLeafRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondError(c, bindError(err, "invalid request body"))
 		return
 	}
 	// Convert to Dto
 	inputDto := application.LeafInputDTO{
-		Note:     req.Note,
-		URL:      req.URL,
-		Platform: req.Platform,
-		Tags:     req.Tags,
+		Title:       req.Title,
+		Description: req.Description,
+		Note:        req.Note,
+		URL:         req.URL,
+		Platform:    req.Platform,
+		Tags:        req.Tags,
 	}
 	// Add Leaf
 	leaf, err := h.Usecase.AddLeaf(c.Request.Context(), &inputDto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	// Response
+	setETag(c, leaf)
 	c.JSON(http.StatusCreated, application.LeafDomainToOutputDTO(leaf))
 }
 
 // PATCH /api/leaves/:id
 func (h *LeafHandler) UpdateLeaf(c *gin.Context) {
 	id := c.Param("id")
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
 	var req UpdateLeafRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondError(c, bindError(err, "invalid request body"))
 		return
 	}
 
 	// Convert to Dto
-	inputDto := application.LeafInputDTO{
-		ID:       id,
-		Note:     req.Note,
-		URL:      req.URL,
-		Platform: req.Platform,
-		Tags:     req.Tags,
+	inputDto := application.LeafUpdateDTO{
+		ID:          id,
+		Title:       req.Title,
+		Description: req.Description,
+		Note:        req.Note,
+		Platform:    req.Platform,
+		Tags:        req.Tags,
+		Version:     version,
 	}
 
-	err := h.Usecase.UpdateLeaf(c.Request.Context(), &inputDto)
+	leaf, err := h.Usecase.UpdateLeaf(c.Request.Context(), &inputDto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	setETag(c, leaf)
 	c.JSON(http.StatusOK, gin.H{"message": "updated"})
 }
 
 // PATCH /api/leaves/:id/read
 func (h *LeafHandler) ReadLeaf(c *gin.Context) {
 	id := c.Param("id")
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-	err := h.Usecase.ReadLeaf(c.Request.Context(), id)
+	leaf, err := h.Usecase.ReadLeaf(c.Request.Context(), id, version)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "marked as read"})
+	setETag(c, leaf)
+	c.JSON(http.StatusOK, application.LeafDomainToOutputDTO(leaf))
+}
+
+// PATCH /api/leaves/:id/unread
+func (h *LeafHandler) UnreadLeaf(c *gin.Context) {
+	id := c.Param("id")
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	leaf, err := h.Usecase.UnreadLeaf(c.Request.Context(), id, version)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setETag(c, leaf)
+	c.JSON(http.StatusOK, application.LeafDomainToOutputDTO(leaf))
+}
+
+// PATCH /api/leaves/:id/status
+func (h *LeafHandler) ChangeLeafStatus(c *gin.Context) {
+	id := c.Param("id")
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	var req ChangeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, "invalid request body"))
+		return
+	}
+
+	leaf, err := h.Usecase.ChangeLeafStatus(c.Request.Context(), id, req.Status, version)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setETag(c, leaf)
+	c.JSON(http.StatusOK, application.LeafDomainToOutputDTO(leaf))
 }
 
 // DELETE /api/leaves/:id
 func (h *LeafHandler) DeleteLeaf(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.Usecase.DeleteLeaf(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.Usecase.DeleteLeaf(c.Request.Context(), id, version); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "moved to trash"})
+}
+
+// POST /api/leaves/:id/restore
+func (h *LeafHandler) RestoreLeaf(c *gin.Context) {
+	id := c.Param("id")
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Successfully deleted"})
+	leaf, err := h.Usecase.RestoreLeaf(c.Request.Context(), id, version)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setETag(c, leaf)
+	c.JSON(http.StatusOK, application.LeafDomainToOutputDTO(leaf))
 }
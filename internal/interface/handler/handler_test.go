@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/umekikazuya/logleaf/internal/application"
+	"github.com/umekikazuya/logleaf/internal/domain"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/memory"
+)
+
+func TestUpdateLeafKeepsOmittedFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID, err := domain.NewUserID("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := domain.WithUserID(context.Background(), userID)
+	repo := memory.NewLeafMemoryRepository()
+	h := NewLeafHandler(application.NewLeafUsecase(repo, memory.NewTagAliasMemoryRepository(), domain.TagPolicy{}, nil, nil))
+	router := gin.New()
+	router.Use(SingleUser(userID))
+	router.PATCH("/api/leaves/:id", h.UpdateLeaf)
+
+	leaf, err := domain.NewLeaf("タイトル", "説明", "メモ", "https://example.com/1", "", []string{"go", "aws"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Put(ctx, leaf); err != nil {
+		t.Fatal(err)
+	}
+	id := leaf.ID().String()
+
+	patch := func(body string) *domain.Leaf {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/api/leaves/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PATCH %s = %d %s", body, w.Code, w.Body)
+		}
+		got, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	tags := func(l *domain.Leaf) []string {
+		var values []string
+		for _, t := range l.Tags() {
+			values = append(values, t.String())
+		}
+		return values
+	}
+
+	// 省略したタグ・説明・メモは変更しない
+	got := patch(`{"title":"新しいタイトル","platform":"web"}`)
+	if got.Title() != "新しいタイトル" || got.Description() != "説明" || got.Note() != "メモ" || !slices.Equal(tags(got), []string{"go", "aws"}) {
+		t.Errorf("after omitting fields: title=%q description=%q note=%q tags=%v", got.Title(), got.Description(), got.Note(), tags(got))
+	}
+	// nullも省略と同じ
+	got = patch(`{"title":"新しいタイトル","platform":"web","tags":null}`)
+	if !slices.Equal(tags(got), []string{"go", "aws"}) {
+		t.Errorf("after tags=null: tags=%v, want [go aws]", tags(got))
+	}
+	// 指定したタグに置き換える
+	got = patch(`{"title":"新しいタイトル","platform":"web","tags":["rust"]}`)
+	if !slices.Equal(tags(got), []string{"rust"}) {
+		t.Errorf("after tags=[rust]: tags=%v, want [rust]", tags(got))
+	}
+	// 空の配列ならすべて外す
+	got = patch(`{"title":"新しいタイトル","platform":"web","tags":[],"description":"","note":""}`)
+	if len(got.Tags()) != 0 || got.Description() != "" || got.Note() != "" {
+		t.Errorf("after clearing: description=%q note=%q tags=%v", got.Description(), got.Note(), tags(got))
+	}
+}
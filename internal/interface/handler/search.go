@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/umekikazuya/logleaf/internal/application"
+)
+
+// GET /api/search
+func (h *LeafHandler) SearchLeaves(c *gin.Context) {
+	var req SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, bindError(err, "invalid query parameters"))
+		return
+	}
+	results, err := h.Usecase.SearchLeaves(c.Request.Context(), req.Query, req.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	outputDTOs := make([]*application.SearchResultOutputDTO, len(results))
+	for i, result := range results {
+		outputDTOs[i] = application.SearchResultToOutputDTO(result)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": outputDTOs})
+}
+
+// POST /api/search/rebuild
+func (h *LeafHandler) RebuildSearchIndex(c *gin.Context) {
+	count, err := h.Usecase.RebuildSearchIndex(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"indexed": count})
+}
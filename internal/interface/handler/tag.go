@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/umekikazuya/logleaf/internal/application"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// GET /api/tags
+func (h *LeafHandler) ListTags(c *gin.Context) {
+	counts, err := h.Usecase.ListTags(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	outputDTOs := make([]*application.TagOutputDTO, len(counts))
+	for i, count := range counts {
+		outputDTOs[i] = application.TagCountToOutputDTO(count)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": outputDTOs})
+}
+
+// GET /api/tags/tree
+func (h *LeafHandler) TagTree(c *gin.Context) {
+	nodes, err := h.Usecase.TagTree(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	outputDTOs := make([]*application.TagNodeOutputDTO, len(nodes))
+	for i, node := range nodes {
+		outputDTOs[i] = application.TagNodeToOutputDTO(node)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": outputDTOs})
+}
+
+// GET /api/tags/:name/leaves
+func (h *LeafHandler) ListTagLeaves(c *gin.Context) {
+	tag := c.Param("name")
+	h.listLeaves(c, func(ctx context.Context, opts domain.ListOptions) ([]domain.Leaf, string, error) {
+		return h.Usecase.ListTagLeaves(ctx, tag, opts)
+	})
+}
+
+// POST /api/tags/:name/rename
+func (h *LeafHandler) RenameTag(c *gin.Context) {
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, "invalid request body"))
+		return
+	}
+	h.runTagOperation(c, func(ctx context.Context, limit int) (*application.TagOperationResult, error) {
+		return h.Usecase.RenameTag(ctx, c.Param("name"), req.To, limit)
+	})
+}
+
+// POST /api/tags/merge
+func (h *LeafHandler) MergeTags(c *gin.Context) {
+	var req MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, "invalid request body"))
+		return
+	}
+	h.runTagOperation(c, func(ctx context.Context, limit int) (*application.TagOperationResult, error) {
+		return h.Usecase.MergeTags(ctx, req.Sources, req.Target, limit)
+	})
+}
+
+// POST /api/tags/:name/move
+func (h *LeafHandler) MoveTag(c *gin.Context) {
+	var req MoveTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, "invalid request body"))
+		return
+	}
+	h.runTagOperation(c, func(ctx context.Context, limit int) (*application.TagOperationResult, error) {
+		return h.Usecase.MoveTag(ctx, c.Param("name"), req.Parent, limit)
+	})
+}
+
+// DELETE /api/tags/:name
+func (h *LeafHandler) DeleteTag(c *gin.Context) {
+	h.runTagOperation(c, func(ctx context.Context, limit int) (*application.TagOperationResult, error) {
+		return h.Usecase.DeleteTag(ctx, c.Param("name"), limit)
+	})
+}
+
+// タグの一括付け替えを1回分実行する
+// doneがfalseなら、クライアントは同じリクエストを繰り返して続きを処理する
+func (h *LeafHandler) runTagOperation(c *gin.Context, run func(ctx context.Context, limit int) (*application.TagOperationResult, error)) {
+	var query TagOperationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, bindError(err, "invalid query parameters"))
+		return
+	}
+	result, err := run(c.Request.Context(), query.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"processed": result.Processed,
+		"done":      result.Done,
+	})
+}
+
+// GET /api/tag-aliases
+func (h *LeafHandler) ListTagAliases(c *gin.Context) {
+	aliases, err := h.Usecase.ListTagAliases(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	outputDTOs := make([]*application.TagAliasOutputDTO, len(aliases))
+	for i, alias := range aliases {
+		outputDTOs[i] = application.TagAliasToOutputDTO(alias)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": outputDTOs})
+}
+
+// PUT /api/tag-aliases/:alias
+func (h *LeafHandler) PutTagAlias(c *gin.Context) {
+	var req PutTagAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, "invalid request body"))
+		return
+	}
+	alias, err := h.Usecase.PutTagAlias(c.Request.Context(), c.Param("alias"), req.Tag)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, application.TagAliasToOutputDTO(*alias))
+}
+
+// DELETE /api/tag-aliases/:alias
+func (h *LeafHandler) DeleteTagAlias(c *gin.Context) {
+	if err := h.Usecase.DeleteTagAlias(c.Request.Context(), c.Param("alias")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
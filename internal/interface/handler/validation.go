@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/umekikazuya/logleaf/internal/domain"
+)
+
+// 検証エラーの項目名を、Goのフィールド名ではなくjson/formタグの名前で報告する
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, key := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+}
+
+// バインドのルールと検証エラーのコード
+var bindingCodes = map[string]string{
+	"required": domain.CodeRequired,
+	"oneof":    domain.CodeUnsupported,
+	"min":      domain.CodeOutOfRange,
+	"max":      domain.CodeOutOfRange,
+}
+
+// ShouldBind系のエラーを項目ごとの検証エラーに変換する
+// 項目を特定できないエラー（JSONの構文エラーなど）はmessageの形式エラーにする
+func bindError(err error, message string) error {
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		errs := make(domain.ValidationErrors, len(fieldErrs))
+		for i, fe := range fieldErrs {
+			code, ok := bindingCodes[fe.Tag()]
+			if !ok {
+				code = domain.CodeInvalidFormat
+			}
+			errs[i] = &domain.ValidationError{Field: fieldPath(fe), Code: code, Message: bindingMessage(code)}
+		}
+		return errs
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &domain.ValidationError{Field: typeErr.Field, Code: domain.CodeInvalidFormat, Message: "値の型が正しくありません"}
+	}
+	return invalidRequest(message)
+}
+
+// 先頭の構造体名を除いた項目のパス（例: tags[3]）
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func bindingMessage(code string) string {
+	switch code {
+	case domain.CodeRequired:
+		return "必須項目です"
+	case domain.CodeUnsupported:
+		return "指定できない値です"
+	case domain.CodeOutOfRange:
+		return "値が範囲外です"
+	default:
+		return "値の形式が正しくありません"
+	}
+}
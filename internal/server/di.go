@@ -2,32 +2,204 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/umekikazuya/logleaf/internal/application"
+	"github.com/umekikazuya/logleaf/internal/domain"
 	"github.com/umekikazuya/logleaf/internal/infrastructure/dynamo"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/memory"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/netguard"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/search"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/sqlite"
+	"github.com/umekikazuya/logleaf/internal/infrastructure/webpage"
 	"github.com/umekikazuya/logleaf/internal/interface/handler"
 )
 
+// 単一ユーザー運用時の利用者（既存データのパーティションUSER#me）
+const defaultUserID = "me"
+
+// ゴミ箱のLeafを保持する日数の既定値
+const defaultTrashRetentionDays = 30
+
+// 全文検索の索引を作り直すまでの分数の既定値
+const defaultSearchIndexTTLMinutes = 10
+
+// ページのメタデータを取得するワーカーの数と、1ページの取得にかける時間の上限
+const (
+	enrichWorkers      = 4
+	enrichFetchTimeout = 10 * time.Second
+)
+
+// ルーティングに必要な依存関係
+type Dependencies struct {
+	LeafHandler *handler.LeafHandler
+	Auth        gin.HandlerFunc
+	Port        string
+}
+
 // アプリケーションの依存関係を初期化
-func InitializeDependencies() (*handler.LeafHandler, string) {
+func InitializeDependencies() *Dependencies {
 	_ = godotenv.Load()
 
-	client, tableName, err := dynamo.NewDynamoClientAndTable(context.Background())
+	leafRepo, aliasRepo, err := newRepositories(context.Background())
 	if err != nil {
 		panic(err)
 	}
-
-	leafRepo := dynamo.NewLeafDynamoRepository(client, tableName)
-	leafUsecase := application.NewLeafUsecase(leafRepo)
+	policy, err := tagPolicy()
+	if err != nil {
+		panic(err)
+	}
+	// 全文検索の索引はメモリ上に持ち、利用者ごとに最初の検索で保存済みのLeafから作成する
+	// 同期バッチなど他のプロセスの書き込みを取り込むため、有効期限を過ぎたら作り直す
+	searchIndex := search.NewIndex()
+	searchIndex.TTL, err = searchIndexTTL()
+	if err != nil {
+		panic(err)
+	}
+	leafRepo = application.NewIndexedLeafRepository(leafRepo, searchIndex)
+	enricher, err := newEnricher(leafRepo)
+	if err != nil {
+		panic(err)
+	}
+	leafUsecase := application.NewLeafUsecase(leafRepo, aliasRepo, policy, enricher, searchIndex)
 	leafHandler := handler.NewLeafHandler(leafUsecase)
 
+	auth, err := newAuthMiddleware()
+	if err != nil {
+		panic(err)
+	}
+
 	// Portを環境変数から取得（デフォルト8080）
 	port := os.Getenv("APP_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	return leafHandler, port
+	return &Dependencies{
+		LeafHandler: leafHandler,
+		Auth:        auth,
+		Port:        port,
+	}
+}
+
+// LEAF_REPOSITORYで選択したリポジトリ（Leafとタグの別名）を生成（デフォルトはdynamo）
+func newRepositories(ctx context.Context) (domain.LeafRepository, domain.TagAliasRepository, error) {
+	retention, err := trashRetention()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch kind := os.Getenv("LEAF_REPOSITORY"); kind {
+	case "", "dynamo":
+		client, tableName, err := dynamo.NewDynamoClientAndTable(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := dynamo.NewLeafDynamoRepository(client, tableName)
+		repo.TrashRetention = retention
+		return repo, dynamo.NewTagAliasDynamoRepository(client, tableName), nil
+	case "memory":
+		repo := memory.NewLeafMemoryRepository()
+		repo.TrashRetention = retention
+		return repo, memory.NewTagAliasMemoryRepository(), nil
+	case "sqlite":
+		db, err := sqlite.NewSQLiteDB(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := sqlite.NewLeafSQLiteRepository(db)
+		repo.TrashRetention = retention
+		return repo, sqlite.NewTagAliasSQLiteRepository(db), nil
+	default:
+		return nil, nil, fmt.Errorf("LEAF_REPOSITORYの値が不正です: %s", kind)
+	}
+}
+
+// タグの正規化の設定
+// TAG_FOLD_CASE（true/false、未設定ならfalse）で大文字・小文字を区別しないタグにする
+// TAG_MAX_LENGTH（未設定なら64）でタグの最大文字数、TAG_ALLOWED_CHARS（正規表現の文字クラスの中身、未設定なら制限なし）で使える文字を決める
+func tagPolicy() (domain.TagPolicy, error) {
+	policy, err := domain.ParseTagPolicy(os.Getenv("TAG_FOLD_CASE"), os.Getenv("TAG_MAX_LENGTH"), os.Getenv("TAG_ALLOWED_CHARS"))
+	if err != nil {
+		return domain.TagPolicy{}, fmt.Errorf("タグの設定（TAG_FOLD_CASE, TAG_MAX_LENGTH, TAG_ALLOWED_CHARS）が不正です: %w", err)
+	}
+	return policy, nil
+}
+
+// ENRICH_METADATA（true/false、未設定ならtrue）がtrueなら、登録したLeafのページのメタデータを取得する
+// 接続先は公開されたアドレスに制限し、FETCH_ALLOWED_NETWORKS（カンマ区切りのCIDR・アドレス）だけ例外として許す
+func newEnricher(repo domain.LeafRepository) (*application.LeafEnricher, error) {
+	raw := os.Getenv("ENRICH_METADATA")
+	if raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("ENRICH_METADATAの値が不正です: %s", raw)
+		}
+		if !enabled {
+			return nil, nil
+		}
+	}
+	allowed, err := netguard.ParsePrefixes(os.Getenv("FETCH_ALLOWED_NETWORKS"))
+	if err != nil {
+		return nil, fmt.Errorf("FETCH_ALLOWED_NETWORKSの値が不正です: %w", err)
+	}
+	client := netguard.New(allowed...).Client(enrichFetchTimeout)
+	return application.NewLeafEnricher(repo, webpage.NewFetcher(client), enrichWorkers), nil
+}
+
+// TRASH_RETENTION_DAYSで指定したゴミ箱の保持期間（未設定なら30日、0なら無期限）
+func trashRetention() (time.Duration, error) {
+	raw := os.Getenv("TRASH_RETENTION_DAYS")
+	if raw == "" {
+		return defaultTrashRetentionDays * 24 * time.Hour, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("TRASH_RETENTION_DAYSの値が不正です: %s", raw)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// SEARCH_INDEX_TTL_MINUTESで指定した全文検索の索引の有効期限（未設定なら10分、0なら作り直さない）
+func searchIndexTTL() (time.Duration, error) {
+	raw := os.Getenv("SEARCH_INDEX_TTL_MINUTES")
+	if raw == "" {
+		return defaultSearchIndexTTLMinutes * time.Minute, nil
+	}
+	minutes, err := strconv.Atoi(raw)
+	if err != nil || minutes < 0 {
+		return 0, fmt.Errorf("SEARCH_INDEX_TTL_MINUTESの値が不正です: %s", raw)
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// AUTH_TOKENS（"token:user,token:user"形式）が設定されていればBearer認証、
+// 未設定なら全リクエストを単一の利用者として扱う
+func newAuthMiddleware() (gin.HandlerFunc, error) {
+	raw := os.Getenv("AUTH_TOKENS")
+	if raw == "" {
+		userID, err := domain.NewUserID(defaultUserID)
+		if err != nil {
+			return nil, err
+		}
+		return handler.SingleUser(userID), nil
+	}
+	tokens := make(map[string]domain.UserID)
+	for _, pair := range strings.Split(raw, ",") {
+		token, user, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || token == "" {
+			return nil, fmt.Errorf("AUTH_TOKENSの形式が不正です: %q", pair)
+		}
+		userID, err := domain.NewUserID(user)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_TOKENSの利用者が不正です: %w", err)
+		}
+		tokens[token] = userID
+	}
+	return handler.Authenticate(tokens), nil
 }
@@ -2,20 +2,39 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/umekikazuya/logleaf/internal/interface/handler"
 )
 
 // ルーティングを設定
-func NewRouter(leafHandler *handler.LeafHandler) *gin.Engine {
+func NewRouter(deps *Dependencies) *gin.Engine {
 	r := gin.Default()
+	// 階層タグ（aws/lambda）をパスパラメータに含められるよう、%2Fを区切りとして扱わない
+	r.UseRawPath = true
 	api := r.Group("/api")
+	api.Use(deps.Auth)
 	{
+		leafHandler := deps.LeafHandler
 		api.GET("/leaves", leafHandler.ListLeaves)
 		api.POST("/leaves", leafHandler.AddLeaf)
 		api.GET("/leaves/:id", leafHandler.GetLeaf)
 		api.PATCH("/leaves/:id", leafHandler.UpdateLeaf)
 		api.PATCH("/leaves/:id/read", leafHandler.ReadLeaf)
+		api.PATCH("/leaves/:id/unread", leafHandler.UnreadLeaf)
+		api.PATCH("/leaves/:id/status", leafHandler.ChangeLeafStatus)
 		api.DELETE("/leaves/:id", leafHandler.DeleteLeaf)
+		api.POST("/leaves/:id/restore", leafHandler.RestoreLeaf)
+		api.GET("/trash", leafHandler.ListTrash)
+		api.GET("/search", leafHandler.SearchLeaves)
+		api.POST("/search/rebuild", leafHandler.RebuildSearchIndex)
+		api.GET("/tags", leafHandler.ListTags)
+		api.GET("/tags/tree", leafHandler.TagTree)
+		api.GET("/tags/:name/leaves", leafHandler.ListTagLeaves)
+		api.POST("/tags/merge", leafHandler.MergeTags)
+		api.POST("/tags/:name/rename", leafHandler.RenameTag)
+		api.POST("/tags/:name/move", leafHandler.MoveTag)
+		api.DELETE("/tags/:name", leafHandler.DeleteTag)
+		api.GET("/tag-aliases", leafHandler.ListTagAliases)
+		api.PUT("/tag-aliases/:alias", leafHandler.PutTagAlias)
+		api.DELETE("/tag-aliases/:alias", leafHandler.DeleteTagAlias)
 	}
 	return r
 }